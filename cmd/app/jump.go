@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"github.com/daicheng123/kubejump/config"
 	"github.com/daicheng123/kubejump/internal/base/data"
 	"github.com/daicheng123/kubejump/internal/entity/utils"
@@ -10,6 +11,8 @@ import (
 	"github.com/daicheng123/kubejump/internal/sshd"
 	"github.com/daicheng123/kubejump/pkg/api"
 	"github.com/daicheng123/kubejump/pkg/exchange"
+	"github.com/daicheng123/kubejump/pkg/proxy"
+	"github.com/daicheng123/kubejump/pkg/session"
 	"k8s.io/klog/v2"
 	"os"
 	"os/signal"
@@ -60,6 +63,7 @@ func (j *JUMP) Stop() {
 
 func bootstrap() {
 	exchange.Initial()
+	exchange.SetSessionTaskHandler(session.HandleTask)
 }
 func RunForever(confPath string) {
 	config.Setup(confPath)
@@ -80,22 +84,37 @@ func RunForever(confPath string) {
 	clusterRepo := repo.NewClusterRepo()
 	podRepo := repo.NewPodRepo()
 	nsRepo := repo.NewNamespaceRepo()
+	loginBlockRepo := repo.NewLoginBlockRepo()
+	permRepo := repo.NewAssetPermissionRepo()
+	userGroupRepo := repo.NewUserGroupRepo()
+	accessRepo := repo.NewAccessRequestRepo()
+	commandRepo := repo.NewCommandRepo()
+	filterRepo := repo.NewCommandFilterRepo()
+	sessionRepo := repo.NewSessionRepo()
+	ftpLogRepo := repo.NewFTPLogRepo()
 
 	// service
 	k8sService, err := service.NewKubernetesService(podRepo, nsRepo)
-	jmsService := service.NewJMService(clusterRepo, userRepo, podRepo)
-	userService := service.NewUserService(userRepo)
+	jmsService := service.NewJMService(clusterRepo, userRepo, podRepo, permRepo, accessRepo, commandRepo, filterRepo, sessionRepo, ftpLogRepo)
+	userService := service.NewUserService(userRepo, loginBlockRepo)
+	permService := service.NewPermissionService(permRepo, userGroupRepo, userRepo, filterRepo)
 
 	if err != nil {
 		klog.Fatalf("init k8s client factory failed, err:[%s]", err.Error())
 	}
 
+	userService.BootstrapAdmins(context.Background(), config.GetConf().AdminUsers)
+
 	srv := NewServer(jmsService, k8sService, userService)
 
 	// start event task
 	go syncClusterResourcesToStore(srv)
 
-	webSrv := httpd.NewServer(jmsService)
+	if config.GetConf().UploadFailedReplay {
+		go proxy.UploadFailedReplays()
+	}
+
+	webSrv := httpd.NewServer(jmsService, userService, permService)
 	api.RegisterWebHandler(webSrv)
 	sshdSrv := sshd.NewSshServer(srv)
 	app := &JUMP{
@@ -9,21 +9,50 @@ import (
 	k8sterminal "github.com/daicheng123/kubejump/pkg/kubernetes/terminal"
 	"github.com/daicheng123/kubejump/pkg/utils"
 	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
 	"k8s.io/klog/v2"
 	"net"
 )
 
 func (s *server) LocalPortForwardingPermission(ctx ssh.Context, destinationHost string, destinationPort uint32) bool {
-	return config.GlobalConfig.EnableLocalPortForward
+	return config.GetConf().EnableLocalPortForward
 }
 
 const ctxID = "ctxID"
 
 func (s *server) PasswordAuth(ctx ssh.Context, password string) bool {
+	if !config.GetConf().TerminalConf.PasswordAuth {
+		return false
+	}
 	ctx.SetValue(ctxID, ctx.SessionID())
 	return auth.SSHPasswordAndPublicKeyAuth(s.userService)(ctx, password, "")
 }
 
+func (s *server) PublicKeyAuth(ctx ssh.Context, key ssh.PublicKey) bool {
+	if !config.GetConf().TerminalConf.PublicKeyAuth {
+		return false
+	}
+	ctx.SetValue(ctxID, ctx.SessionID())
+	publicKey := string(gossh.MarshalAuthorizedKey(key))
+	return auth.SSHPasswordAndPublicKeyAuth(s.userService)(ctx, "", publicKey)
+}
+
+func (s *server) KeyboardInteractiveAuth(ctx ssh.Context, challenger gossh.KeyboardInteractiveChallenge) bool {
+	// keyboard-interactive 的第一因子只能是密码
+	if !config.GetConf().TerminalConf.PasswordAuth {
+		return false
+	}
+	ctx.SetValue(ctxID, ctx.SessionID())
+	return auth.SSHKeyboardInteractiveAuth(s.userService)(ctx, challenger)
+}
+
+func (s *server) AuthLogCallback(ctx ssh.Context, conn gossh.ConnMetadata, method string, err error) {
+	if err == nil {
+		klog.Infof("SSH conn[%s] user %s authenticated by %s", ctx.SessionID(), conn.User(), method)
+		auth.SSHAuthSucceeded(s.userService, ctx, conn)
+	}
+}
+
 func (s *server) GetSSHSigner() ssh.Signer {
 	klog.Infoln(k8sterminal.TERMINAL_HOST_KEY)
 	singer, err := sshd.ParsePrivateKeyFromString(k8sterminal.TERMINAL_HOST_KEY)
@@ -46,7 +75,31 @@ func (s *server) SessionHandler(sess ssh.Session) {
 		klog.Infof("User %s request pty %s", sess.User(), pty.Term)
 		go interactiveSrv.WatchWinSizeChange(winChan)
 		interactiveSrv.Dispatch()
+	} else {
+		klog.Infof("User %s request exec %q", sess.User(), sess.RawCommand())
+		_ = sess.Exit(handler.NewExecHandler(sess, user, s.jmsService).Run())
+	}
+}
+
+// SFTPHandler 处理 sftp 子系统请求, 以 /cluster/namespace/pod/container 的目录结构访问容器内文件
+func (s *server) SFTPHandler(sess ssh.Session) {
+	user, ok := sess.Context().Value(auth.ContextKeyUser).(*entity.User)
+	if !ok || user.ID == 0 {
+		klog.Errorf("SFTP User %s not found, exit.", sess.User())
+		return
+	}
+	handler.NewSftpHandler(sess, user, s.jmsService).Serve()
+}
+
+// DirectTCPIPHandler 处理 ssh -L 本地转发请求, 目标主机为 [cluster/namespace/]pod
+func (s *server) DirectTCPIPHandler(srv *ssh.Server, conn *gossh.ServerConn, newChan gossh.NewChannel, ctx ssh.Context) {
+	user, ok := ctx.Value(auth.ContextKeyUser).(*entity.User)
+	if !ok || user.ID == 0 {
+		klog.Errorf("Port forward user %s not found, reject.", ctx.User())
+		_ = newChan.Reject(gossh.Prohibited, "not auth user")
+		return
 	}
+	handler.NewPortForwardHandler(ctx, user, s.jmsService).Serve(srv, newChan)
 }
 
 func (s *server) GetSSHAddr() string {
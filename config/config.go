@@ -1,17 +1,22 @@
 package config
 
 import (
+	"errors"
 	"fmt"
 	"github.com/daicheng123/kubejump/internal/entity"
 	"github.com/spf13/viper"
 	"k8s.io/klog/v2"
+	"math"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
 const (
 	hostEnvKey = "SERVER_HOSTNAME"
+
+	defaultZipMaxSize = "1024M"
 )
 
 var GlobalConfig *Config
@@ -46,24 +51,52 @@ type Config struct {
 
 	EnableLocalPortForward bool `mapstructure:"enable_local_port_forward"`
 
-	RootPath       string
-	LogDirPath     string
-	LocalCachePath string
+	K8sSkipTls bool `mapstructure:"k8s_skip_tls"` // exec 进入 pod 时跳过 API Server 证书校验
+
+	SftpShowHiddenFile bool `mapstructure:"sftp_show_hidden_file"` // SFTP 列出容器内目录时是否显示隐藏文件
+
+	ZipMaxSize string `mapstructure:"zip_max_size"` // rz/sz 传输单个文件的大小上限, 支持 K、M、G 后缀
+
+	LoginFailedLimit  int `mapstructure:"login_failed_limit"`  // 连续登录失败次数上限, 0 表示不限制
+	LoginFailedWindow int `mapstructure:"login_failed_window"` // 失败次数统计窗口 (单位: 分钟)
+	LoginBlockTime    int `mapstructure:"login_block_time"`    // 锁定时长 (单位: 分钟)
+
+	// 启动时设置为管理员的用户名, 用于首次部署及升级到区分角色的版本后指定初始管理员
+	AdminUsers []string `mapstructure:"admin_users"`
+
+	AccessRequestTimeout     int `mapstructure:"access_request_timeout"`      // 等待审批的超时时间 (单位: 分钟)
+	AccessRequestMaxDuration int `mapstructure:"access_request_max_duration"` // 单次申请的最大授权时长 (单位: 分钟)
+
+	RootPath         string
+	LogDirPath       string
+	LocalCachePath   string
+	ReplayFolderPath string `mapstructure:"replay_folder_path"`
+
+	UploadFailedReplay bool `mapstructure:"upload_failed_replay_on_start"` // 启动时重新上传之前上传失败的录像
+
+	// 会话共享, 多实例部署时使用 redis 在实例间转发会话数据
+	ShareRoomType         string   `mapstructure:"share_room_type"` // local, redis
+	RedisHost             string   `mapstructure:"redis_host"`
+	RedisPort             string   `mapstructure:"redis_port"`
+	RedisPassword         string   `mapstructure:"redis_password"`
+	RedisDBIndex          int      `mapstructure:"redis_db_index"`
+	RedisClusters         []string `mapstructure:"redis_clusters"`
+	RedisSentinelPassword string   `mapstructure:"redis_sentinel_password"`
+	RedisSentinelHosts    string   `mapstructure:"redis_sentinel_hosts"` // 格式: mymaster/host1:26379,host2:26379
+	RedisUseSSL           bool     `mapstructure:"redis_use_ssl"`
+	CertsFolderPath       string   `mapstructure:"certs_folder_path"` // redis_ca.crt, redis_client.crt, redis_client.key
 
 	TerminalConf *entity.TerminalConfig
 
 	//DataFolderPath    string
 	//KeyFolderPath     string
 	//AccessKeyFilePath string
-	//ReplayFolderPath  string
 
 	//CoreHost       string `mapstructure:"CORE_HOST"`
 	//BootstrapToken string `mapstructure:"BOOTSTRAP_TOKEN"`
 	//Comment             string `mapstructure:"COMMENT"`
 	//LanguageCode        string `mapstructure:"LANGUAGE_CODE"`
-	//UploadFailedReplay  bool   `mapstructure:"UPLOAD_FAILED_REPLAY_ON_START"`
 	//AssetLoadPolicy     string `mapstructure:"ASSET_LOAD_POLICY"` // all
-	//ZipMaxSize          string `mapstructure:"ZIP_MAX_SIZE"`
 	//ZipTmpPath          string `mapstructure:"ZIP_TMP_PATH"`
 	//ClientAliveInterval int    `mapstructure:"CLIENT_ALIVE_INTERVAL"`
 	//RetryAliveCountMax  int    `mapstructure:"RETRY_ALIVE_COUNT_MAX"`
@@ -85,6 +118,12 @@ func Setup(configPath string) {
 	loadConfigFromEnv(&conf)
 	loadConfigFromFile(configPath, &conf)
 
+	// 大小上限写错时使用默认值, 避免误当作不限制
+	if _, err := parseSize(conf.ZipMaxSize); err != nil {
+		klog.Warningf("Invalid zip_max_size %q, use default %s: %s", conf.ZipMaxSize, defaultZipMaxSize, err)
+		conf.ZipMaxSize = defaultZipMaxSize
+	}
+
 	GlobalConfig = &conf
 	klog.Infof("%+v\n", GlobalConfig)
 }
@@ -95,8 +134,10 @@ func getDefaultConfig() Config {
 
 	dataFolderPath := filepath.Join(rootPath, "data")
 	localCachePath := filepath.Join(dataFolderPath, "cache.txt")
+	replayFolderPath := filepath.Join(dataFolderPath, "replays")
+	certsFolderPath := filepath.Join(dataFolderPath, "certs")
 
-	folders := []string{dataFolderPath}
+	folders := []string{dataFolderPath, replayFolderPath}
 	for i := range folders {
 		if err := EnsureDirExist(folders[i]); err != nil {
 			klog.Fatalf("Create folder failed: %s", err.Error())
@@ -119,7 +160,20 @@ func getDefaultConfig() Config {
 		DatabasePort:           3306,
 		DatabasePassword:       "root",
 		LocalCachePath:         localCachePath,
+		ReplayFolderPath:       replayFolderPath,
+		UploadFailedReplay:     true,
+		ShareRoomType:          "local",
+		RedisHost:              "127.0.0.1",
+		RedisPort:              "6379",
+		CertsFolderPath:        certsFolderPath,
 		AssetLoadPolicy:        "all",
+		ZipMaxSize:             defaultZipMaxSize,
+		LoginFailedLimit:       5,
+		LoginFailedWindow:      10,
+		LoginBlockTime:         30,
+
+		AccessRequestTimeout:     10,
+		AccessRequestMaxDuration: 480,
 
 		ClientAliveInterval: 120,
 		// terminal 终端配置
@@ -128,10 +182,50 @@ func getDefaultConfig() Config {
 			AssetListSortBy:   "ClusterName",
 			MaxIdleTime:       60,
 			MaxSessionTime:    36000,
+			PasswordAuth:      true,
+			PublicKeyAuth:     true,
+
+			EnableSessionShare: true,
+			ReplayStorage: entity.ReplayConfig{
+				TypeName: entity.ReplayStorageLocal,
+			},
 		},
 	}
 }
 
+// ZipMaxSizeBytes 将 ZipMaxSize 转换为字节数, 配置为 0 表示不限制, 无法解析时使用默认值
+func (c Config) ZipMaxSizeBytes() int64 {
+	size, err := parseSize(c.ZipMaxSize)
+	if err != nil {
+		size, _ = parseSize(defaultZipMaxSize)
+	}
+	return size
+}
+
+var errInvalidSize = errors.New("invalid size, expect a number with optional K, M or G suffix")
+
+func parseSize(s string) (int64, error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	s = strings.TrimSuffix(s, "B")
+	unit := int64(1)
+	switch {
+	case strings.HasSuffix(s, "K"):
+		unit = 1 << 10
+	case strings.HasSuffix(s, "M"):
+		unit = 1 << 20
+	case strings.HasSuffix(s, "G"):
+		unit = 1 << 30
+	}
+	if unit > 1 {
+		s = s[:len(s)-1]
+	}
+	size, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || size < 0 || size > math.MaxInt64/unit {
+		return 0, errInvalidSize
+	}
+	return size * unit, nil
+}
+
 func getDefaultName() string {
 	hostname, _ := os.Hostname()
 	if serverHostname, ok := os.LookupEnv(hostEnvKey); ok {
@@ -0,0 +1,29 @@
+package config
+
+import "testing"
+
+func TestZipMaxSizeBytes(t *testing.T) {
+	tests := []struct {
+		size string
+		want int64
+	}{
+		{size: "1024M", want: 1024 << 20},
+		{size: "512k", want: 512 << 10},
+		{size: "2GB", want: 2 << 30},
+		{size: " 100 ", want: 100},
+		{size: "0", want: 0},
+		// 无法解析时使用默认的 1024M, 不能当作不限制
+		{size: "", want: 1024 << 20},
+		{size: "10X", want: 1024 << 20},
+		{size: "1.5G", want: 1024 << 20},
+		{size: "-1M", want: 1024 << 20},
+		{size: "99999999999G", want: 1024 << 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.size, func(t *testing.T) {
+			if got := (Config{ZipMaxSize: tt.size}).ZipMaxSizeBytes(); got != tt.want {
+				t.Errorf("ZipMaxSizeBytes(%q) = %d, want %d", tt.size, got, tt.want)
+			}
+		})
+	}
+}
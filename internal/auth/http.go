@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+var (
+	ErrLoginBlocked = errors.New("login blocked, please try again later")
+	ErrLoginFailed  = errors.New("invalid username, password or OTP code")
+)
+
+// HTTPBasicAuth 校验管理接口的 Basic 认证, 与 ssh 登录共用失败计数及锁定,
+// 开启 MFA 的用户需要同时提供动态码
+func HTTPBasicAuth(ctx context.Context, userService *service.UserService, username, password, otpCode, remoteAddr string) (*entity.User, error) {
+	if userService.IsLoginBlocked(ctx, username, remoteAddr) {
+		klog.Warningf("HTTP user %s rejected from %s: login blocked", username, remoteAddr)
+		return nil, ErrLoginBlocked
+	}
+	user, err := userService.GetUserInfoByName(ctx, username)
+	if err != nil || !user.IsActive {
+		userService.RecordLoginFailed(ctx, username, remoteAddr)
+		return nil, ErrLoginFailed
+	}
+	ok, _ := verifyPassword(user.Password, password)
+	if ok && user.MFAEnabled() {
+		ok = user.OTPSecretKey != "" && validateTOTP(user.OTPSecretKey, otpCode, time.Now())
+	}
+	if !ok {
+		klog.Infof("HTTP user %s authentication failed from %s", username, remoteAddr)
+		userService.RecordLoginFailed(ctx, username, remoteAddr)
+		return nil, ErrLoginFailed
+	}
+	userService.ClearLoginFailed(ctx, username, remoteAddr)
+	return user, nil
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"k8s.io/klog/v2"
+	"net"
+	"time"
+)
+
+const (
+	maxMFAAttempts = 3
+
+	passwordQuestion = "Password: "
+)
+
+var (
+	otpEnrollInstruction = "MFA is enabled for your account, please bind an authenticator app.\n" +
+		"Secret key: %s\n" +
+		"Or import the url: %s\n" +
+		"Then enter the code shown in the app to finish binding."
+)
+
+type SSHKeyboardInteractiveAuthFunc func(ctx ssh.Context, challenger gossh.KeyboardInteractiveChallenge) bool
+
+// SSHKeyboardInteractiveAuth 通过 keyboard-interactive 完成 密码 + TOTP 的校验,
+// 若第一因子已经通过 password 完成, 则只询问动态码
+func SSHKeyboardInteractiveAuth(userService *service.UserService) SSHKeyboardInteractiveAuthFunc {
+	passwordAuth := SSHPasswordAndPublicKeyAuth(userService)
+
+	return func(ctx ssh.Context, challenger gossh.KeyboardInteractiveChallenge) bool {
+		remoteAddr, _, _ := net.SplitHostPort(ctx.RemoteAddr().String())
+		user, ok := ctx.Value(ContextKeyMFAUser).(*entity.User)
+		if ok && userService.IsLoginBlocked(ctx, ctx.User(), remoteAddr) {
+			klog.Warningf("SSH conn[%s] user %s MFA rejected from %s: login blocked", ctx.SessionID(), ctx.User(), remoteAddr)
+			ctx.SetValue(ContextKeyMFAUser, nil)
+			return false
+		}
+		if !ok {
+			answers, err := challenger(ctx.User(), "", []string{passwordQuestion}, []bool{false})
+			if err != nil || len(answers) != 1 {
+				return false
+			}
+			if passwordAuth(ctx, answers[0], "") {
+				return true
+			}
+			if user, ok = ctx.Value(ContextKeyMFAUser).(*entity.User); !ok {
+				return false
+			}
+		}
+
+		if user.OTPSecretKey == "" {
+			ok = enrollOTP(ctx, userService, user, remoteAddr, challenger)
+		} else {
+			ok = checkOTP(ctx, userService, user, remoteAddr, user.OTPSecretKey, mfaOptionInstruction, challenger)
+		}
+		if !ok {
+			// 动态码校验失败后需重新校验密码
+			ctx.SetValue(ContextKeyMFAUser, nil)
+			klog.Infof("SSH conn[%s] user %s MFA failed", ctx.SessionID(), ctx.User())
+			return false
+		}
+		ctx.SetValue(ContextKeyUser, user)
+		klog.Infof("SSH conn[%s] authenticating user %s MFA success", ctx.SessionID(), ctx.User())
+		return true
+	}
+}
+
+// checkOTP 每次输错动态码都计入登录失败次数, 达到锁定阈值后不再询问
+func checkOTP(ctx ssh.Context, userService *service.UserService, user *entity.User, remoteAddr, secret, instruction string,
+	challenger gossh.KeyboardInteractiveChallenge) bool {
+	question := fmt.Sprintf(mfaOptionQuestion, "OTP")
+	for i := 0; i < maxMFAAttempts; i++ {
+		answers, err := challenger(user.Username, instruction, []string{question}, []bool{true})
+		if err != nil || len(answers) != 1 {
+			return false
+		}
+		if validateTOTP(secret, answers[0], time.Now()) {
+			return true
+		}
+		klog.Infof("SSH conn[%s] user %s enter invalid OTP code", ctx.SessionID(), user.Username)
+		userService.RecordLoginFailed(ctx, ctx.User(), remoteAddr)
+		if userService.IsLoginBlocked(ctx, ctx.User(), remoteAddr) {
+			return false
+		}
+	}
+	return false
+}
+
+// enrollOTP 首次登录时为用户生成并绑定 TOTP 密钥
+func enrollOTP(ctx ssh.Context, userService *service.UserService, user *entity.User, remoteAddr string,
+	challenger gossh.KeyboardInteractiveChallenge) bool {
+	secret, err := generateOTPSecret()
+	if err != nil {
+		klog.Errorf("Generate user %s OTP secret failed: %s", user.Username, err)
+		return false
+	}
+	instruction := fmt.Sprintf(otpEnrollInstruction, secret, otpAuthURL(user.Username, secret))
+	if !checkOTP(ctx, userService, user, remoteAddr, secret, instruction, challenger) {
+		return false
+	}
+	if err = userService.BindOTPSecret(ctx, user, secret); err != nil {
+		klog.Errorf("Bind user %s OTP secret failed: %s", user.Username, err)
+		return false
+	}
+	klog.Infof("SSH conn[%s] user %s bind OTP success", ctx.SessionID(), user.Username)
+	return true
+}
@@ -6,7 +6,7 @@ import (
 )
 
 var (
-	confirmInstruction = "Please wait for your admin to confirm."
+	ConfirmInstruction = "Please wait for your admin to confirm."
 	confirmQuestion    = "Do you want to continue [Y/n]? : "
 )
 
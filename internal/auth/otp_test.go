@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"testing"
+	"time"
+)
+
+// rfc6238Secret RFC 6238 附录 B 中 SHA1 测试向量使用的密钥 "12345678901234567890"
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// RFC 6238 附录 B 的 8 位动态码取后 6 位
+var rfc6238Vectors = []struct {
+	unix int64
+	code string
+}{
+	{unix: 59, code: "287082"},
+	{unix: 1111111109, code: "081804"},
+	{unix: 1111111111, code: "050471"},
+	{unix: 1234567890, code: "005924"},
+	{unix: 2000000000, code: "279037"},
+	{unix: 20000000000, code: "353130"},
+}
+
+func TestHOTPCode(t *testing.T) {
+	key, err := otpEncoding.DecodeString(rfc6238Secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range rfc6238Vectors {
+		if got := hotpCode(key, uint64(v.unix/otpPeriod)); got != v.code {
+			t.Errorf("hotpCode at %d = %s, want %s", v.unix, got, v.code)
+		}
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	tests := []struct {
+		name   string
+		secret string
+		code   string
+		now    time.Time
+		want   bool
+	}{
+		{name: "current step", secret: rfc6238Secret, code: "050471", now: now, want: true},
+		{name: "lowercase secret and spaces", secret: " gezdgnbvgy3tqojqgezdgnbvgy3tqojq ", code: " 050471 ", now: now, want: true},
+		{name: "previous step allowed", secret: rfc6238Secret, code: "050471", now: now.Add(otpPeriod * time.Second), want: true},
+		{name: "next step allowed", secret: rfc6238Secret, code: "050471", now: now.Add(-otpPeriod * time.Second), want: true},
+		{name: "two steps late", secret: rfc6238Secret, code: "050471", now: now.Add(2 * otpPeriod * time.Second), want: false},
+		{name: "wrong code", secret: rfc6238Secret, code: "050472", now: now, want: false},
+		{name: "eight digits", secret: rfc6238Secret, code: "14050471", now: now, want: false},
+		{name: "empty code", secret: rfc6238Secret, code: "", now: now, want: false},
+		{name: "invalid secret", secret: "not base32!", code: "050471", now: now, want: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := validateTOTP(tt.secret, tt.code, tt.now); got != tt.want {
+				t.Errorf("validateTOTP(%q, %q) = %v, want %v", tt.secret, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateOTPSecret(t *testing.T) {
+	secret, err := generateOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := otpEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Fatalf("secret %q decode %d bytes, err %v", secret, len(key), err)
+	}
+	code := hotpCode(key, uint64(time.Now().Unix()/otpPeriod))
+	if !validateTOTP(secret, code, time.Now()) {
+		t.Errorf("generated secret rejects its own code %s", code)
+	}
+}
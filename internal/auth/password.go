@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// verifyPassword 校验用户密码, legacy 表示数据库中存储的仍是明文, 需要在登录成功后重新哈希
+func verifyPassword(secret, password string) (ok bool, legacy bool) {
+	if secret == "" || password == "" {
+		return false, false
+	}
+	if utils.IsHashedPassword(secret) {
+		return bcrypt.CompareHashAndPassword([]byte(secret), []byte(password)) == nil, false
+	}
+	return subtle.ConstantTimeCompare([]byte(secret), []byte(password)) == 1, true
+}
@@ -0,0 +1,37 @@
+package auth
+
+import (
+	"testing"
+
+	"github.com/daicheng123/kubejump/pkg/utils"
+)
+
+func TestVerifyPassword(t *testing.T) {
+	hashed, err := utils.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name       string
+		secret     string
+		password   string
+		wantOK     bool
+		wantLegacy bool
+	}{
+		{name: "bcrypt", secret: hashed, password: "secret", wantOK: true},
+		{name: "bcrypt wrong password", secret: hashed, password: "Secret"},
+		{name: "legacy plaintext", secret: "secret", password: "secret", wantOK: true, wantLegacy: true},
+		{name: "legacy wrong password", secret: "secret", password: "secret1", wantLegacy: true},
+		{name: "hash as password", secret: hashed, password: hashed},
+		{name: "empty password", secret: hashed, password: ""},
+		{name: "empty secret", secret: "", password: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ok, legacy := verifyPassword(tt.secret, tt.password)
+			if ok != tt.wantOK || legacy != tt.wantLegacy {
+				t.Errorf("verifyPassword = (%v, %v), want (%v, %v)", ok, legacy, tt.wantOK, tt.wantLegacy)
+			}
+		})
+	}
+}
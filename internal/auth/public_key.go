@@ -0,0 +1,34 @@
+package auth
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	gossh "golang.org/x/crypto/ssh"
+	"k8s.io/klog/v2"
+)
+
+// matchUserPublicKey 校验登录公钥的指纹是否在用户已登记的公钥中
+func matchUserPublicKey(ctx context.Context, userService *service.UserService, user *entity.User, publicKey string) bool {
+	if publicKey == "" || user == nil {
+		return false
+	}
+	pubKey, _, _, _, err := gossh.ParseAuthorizedKey([]byte(publicKey))
+	if err != nil {
+		klog.Errorf("Parse user %s public key failed: %s", user.Username, err)
+		return false
+	}
+	fingerprint := gossh.FingerprintSHA256(pubKey)
+
+	keys, err := userService.ListUserPublicKeys(ctx, user.ID)
+	if err != nil {
+		klog.Errorf("List user %s public keys failed: %s", user.Username, err)
+		return false
+	}
+	for i := range keys {
+		if keys[i].Fingerprint == fingerprint {
+			return true
+		}
+	}
+	return false
+}
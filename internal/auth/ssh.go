@@ -4,6 +4,7 @@ import (
 	"github.com/daicheng123/kubejump/internal/entity"
 	"github.com/daicheng123/kubejump/internal/service"
 	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
 	"k8s.io/klog/v2"
 	"net"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 const (
 	ContextKeyUser              = "CONTEXT_USER"
+	ContextKeyMFAUser           = "CONTEXT_MFA_USER"
 	ContextKeyDirectLoginFormat = "CONTEXT_DIRECT_LOGIN_FORMAT"
 
 	SeparatorATSign   = "@"
@@ -24,6 +26,8 @@ type LoginAssetReq struct {
 	SysUserInfo string
 	AssetInfo   string
 	Info        *entity.ConnectTokenInfo
+
+	needRehash bool
 }
 
 func (lr *LoginAssetReq) IsToken() bool {
@@ -31,7 +35,9 @@ func (lr *LoginAssetReq) IsToken() bool {
 }
 
 func (lr *LoginAssetReq) Authenticate(password string) bool {
-	return lr.Info.Secret == password
+	ok, legacy := verifyPassword(lr.Info.Secret, password)
+	lr.needRehash = ok && legacy
+	return ok
 }
 
 type SSHAuthFunc func(ctx ssh.Context, password, publicKey string) (res bool)
@@ -41,22 +47,66 @@ func SSHPasswordAndPublicKeyAuth(userService *service.UserService) SSHAuthFunc {
 	return func(ctx ssh.Context, password, publicKey string) (res bool) {
 		remoteAddr, _, _ := net.SplitHostPort(ctx.RemoteAddr().String())
 
-		authMethod := "publickey"
-		if password != "" {
-			authMethod = "password"
+		// 按是否携带公钥区分认证方式, 空密码同样按密码失败计数
+		authMethod := "password"
+		if publicKey != "" {
+			authMethod = "publickey"
+		}
+
+		if userService.IsLoginBlocked(ctx, ctx.User(), remoteAddr) {
+			klog.Warningf("SSH conn[%s] user %s %s rejected from %s: login blocked", ctx.SessionID(), ctx.User(), authMethod, remoteAddr)
+			return false
 		}
 
-		if req, ok := parseLoginReq(userService, ctx); ok {
-			if req.IsToken() && req.Authenticate(password) {
+		// 每次第一因子尝试都重新判定, 避免此前的结果残留
+		ctx.SetValue(ContextKeyMFAUser, nil)
+		if req, ok := parseLoginReq(userService, ctx); ok && req.IsToken() {
+			var authenticated bool
+			switch authMethod {
+			case "password":
+				authenticated = req.Authenticate(password)
+			default:
+				// 公钥回调在客户端未签名的探测请求中同样会被调用, 此时无法确认持有私钥,
+				// 因此公钥不能作为 MFA 的第一因子, 开启 MFA 的用户需通过 密码 + 动态码 登录
+				if req.Info.User.MFAEnabled() {
+					klog.Infof("SSH conn[%s] user %s publickey rejected from %s: MFA requires password", ctx.SessionID(), ctx.User(), remoteAddr)
+					return false
+				}
+				authenticated = matchUserPublicKey(ctx, userService, req.Info.User, publicKey)
+			}
+			if authenticated {
+				if req.needRehash {
+					if err := userService.RehashPassword(ctx, req.Info.User, password); err != nil {
+						klog.Errorf("Rehash user %s legacy password failed: %s", ctx.User(), err)
+					}
+				}
+				if req.Info.User.MFAEnabled() {
+					// 第一因子通过, 等待 keyboard-interactive 完成 MFA 校验
+					ctx.SetValue(ContextKeyMFAUser, req.Info.User)
+					klog.Infof("SSH conn[%s] user %s %s accepted from %s, require MFA", ctx.SessionID(), ctx.User(), authMethod, remoteAddr)
+					return false
+				}
 				ctx.SetValue(ContextKeyUser, req.Info.User)
 				klog.Infof("SSH conn[%s] authenticating user %s %s from %s", ctx.SessionID(), ctx.User(), authMethod, remoteAddr)
 				return true
 			}
 		}
+		if authMethod == "password" {
+			// 公钥认证时客户端会依次尝试多把密钥, 只统计密码失败次数
+			userService.RecordLoginFailed(ctx, ctx.User(), remoteAddr)
+		}
+		klog.Infof("SSH conn[%s] user %s %s authentication failed from %s", ctx.SessionID(), ctx.User(), authMethod, remoteAddr)
 		return false
 	}
 }
 
+// SSHAuthSucceeded 认证全部完成 (公钥已验签、MFA 已通过) 后才清除登录失败次数,
+// 公钥探测请求及仅通过第一因子都不会重置计数
+func SSHAuthSucceeded(userService *service.UserService, ctx ssh.Context, conn gossh.ConnMetadata) {
+	remoteAddr, _, _ := net.SplitHostPort(conn.RemoteAddr().String())
+	userService.ClearLoginFailed(ctx, conn.User(), remoteAddr)
+}
+
 func parseLoginReq(userService *service.UserService, ctx ssh.Context) (*LoginAssetReq, bool) {
 	if req, ok := ctx.Value(ContextKeyDirectLoginFormat).(*LoginAssetReq); ok {
 		return req, true
@@ -100,6 +150,10 @@ func parseUserFormatBySeparator(s, Separator string) (LoginAssetReq, bool) {
 
 func parseJMSTokenLoginReq(userService *service.UserService, ctx ssh.Context) (*LoginAssetReq, bool) {
 	if userInfo, err := userService.GetUserInfoByName(ctx, ctx.User()); err == nil {
+		if !userInfo.IsActive {
+			klog.Warningf("Check user token %s failed: user is disabled", ctx.User())
+			return nil, false
+		}
 		var assetReq = &LoginAssetReq{
 			Username:    userInfo.Username,
 			SysUserInfo: userInfo.Name,
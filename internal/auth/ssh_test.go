@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gliderlabs/ssh"
+)
+
+type fakeSSHContext struct {
+	context.Context
+	sync.Mutex
+	user   string
+	values map[interface{}]interface{}
+}
+
+func newFakeSSHContext(user string) *fakeSSHContext {
+	return &fakeSSHContext{
+		Context: context.Background(),
+		user:    user,
+		values:  make(map[interface{}]interface{}),
+	}
+}
+
+func (c *fakeSSHContext) Value(key interface{}) interface{} {
+	if value, ok := c.values[key]; ok {
+		return value
+	}
+	return c.Context.Value(key)
+}
+
+func (c *fakeSSHContext) SetValue(key, value interface{}) { c.values[key] = value }
+func (c *fakeSSHContext) User() string                    { return c.user }
+func (c *fakeSSHContext) SessionID() string               { return "test" }
+func (c *fakeSSHContext) ClientVersion() string           { return "" }
+func (c *fakeSSHContext) ServerVersion() string           { return "" }
+func (c *fakeSSHContext) Permissions() *ssh.Permissions   { return nil }
+func (c *fakeSSHContext) LocalAddr() net.Addr             { return &net.TCPAddr{} }
+func (c *fakeSSHContext) RemoteAddr() net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP("10.0.0.1"), Port: 50000}
+}
+
+type fakeUserRepo struct {
+	entity.UserRepo
+	users map[string]*entity.User
+	// 记录重新哈希后写入的密码
+	updated map[uint]string
+}
+
+func (r *fakeUserRepo) GetInfoByName(_ context.Context, username string, user *entity.User) error {
+	found, ok := r.users[username]
+	if !ok {
+		return errors.New("record not found")
+	}
+	*user = *found
+	return nil
+}
+
+func (r *fakeUserRepo) UpdatePassword(_ context.Context, userID uint, hashedPassword string) error {
+	r.updated[userID] = hashedPassword
+	return nil
+}
+
+func (r *fakeUserRepo) ListPublicKeys(context.Context, uint) ([]*entity.UserPublicKey, error) {
+	return nil, nil
+}
+
+// fakeLoginBlockRepo 只统计失败次数
+type fakeLoginBlockRepo struct {
+	entity.LoginBlockRepo
+	counts map[string]int
+}
+
+func (r *fakeLoginBlockRepo) IncrFailedCount(_ context.Context, key string, _ time.Duration) (int, error) {
+	r.counts[key]++
+	return r.counts[key], nil
+}
+
+func (r *fakeLoginBlockRepo) IsBlocked(context.Context, string) bool                         { return false }
+func (r *fakeLoginBlockRepo) AddFailedSource(context.Context, string, string, time.Duration) {}
+
+func TestSSHPasswordAndPublicKeyAuth(t *testing.T) {
+	config.GlobalConfig = &config.Config{LoginFailedLimit: 100, LoginFailedWindow: 10, LoginBlockTime: 30}
+	defer func() { config.GlobalConfig = nil }()
+	hashed, err := utils.HashPassword("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	users := map[string]*entity.User{
+		"alice": {Username: "alice", Password: hashed, IsActive: true},
+		"bob":   {Username: "bob", Password: hashed, IsActive: true, OTPLevel: entity.OTPLevelEnabled},
+		"carol": {Username: "carol", Password: hashed},
+	}
+	tests := []struct {
+		name      string
+		user      string
+		password  string
+		publicKey string
+		want      bool
+		wantMFA   bool
+		// 期望计入的失败次数
+		wantFailed int
+	}{
+		{name: "password", user: "alice", password: "secret", want: true},
+		{name: "wrong password", user: "alice", password: "wrong", wantFailed: 1},
+		{name: "empty password", user: "alice", password: "", wantFailed: 1},
+		{name: "unknown public key", user: "alice", publicKey: "ssh-ed25519 AAAA", wantFailed: 0},
+		{name: "mfa first factor", user: "bob", password: "secret", wantMFA: true},
+		{name: "mfa public key", user: "bob", publicKey: "ssh-ed25519 AAAA", wantFailed: 0},
+		{name: "disabled user", user: "carol", password: "secret", wantFailed: 1},
+		{name: "unknown user", user: "dave", password: "secret", wantFailed: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blockRepo := &fakeLoginBlockRepo{counts: make(map[string]int)}
+			userService := service.NewUserService(&fakeUserRepo{users: users, updated: map[uint]string{}}, blockRepo)
+			ctx := newFakeSSHContext(tt.user)
+			if got := SSHPasswordAndPublicKeyAuth(userService)(ctx, tt.password, tt.publicKey); got != tt.want {
+				t.Errorf("auth = %v, want %v", got, tt.want)
+			}
+			if _, gotMFA := ctx.Value(ContextKeyMFAUser).(*entity.User); gotMFA != tt.wantMFA {
+				t.Errorf("mfa user set = %v, want %v", gotMFA, tt.wantMFA)
+			}
+			if got := blockRepo.counts["user:"+tt.user]; got != tt.wantFailed {
+				t.Errorf("failed count = %d, want %d", got, tt.wantFailed)
+			}
+		})
+	}
+}
+
+func TestSSHPasswordAuthRehashLegacyPassword(t *testing.T) {
+	config.GlobalConfig = &config.Config{}
+	defer func() { config.GlobalConfig = nil }()
+	legacy := &entity.User{BaseModel: entity.BaseModel{ID: 1}, Username: "alice", Password: "secret", IsActive: true}
+	userRepo := &fakeUserRepo{users: map[string]*entity.User{"alice": legacy}, updated: map[uint]string{}}
+	userService := service.NewUserService(userRepo, &fakeLoginBlockRepo{counts: make(map[string]int)})
+	auth := SSHPasswordAndPublicKeyAuth(userService)
+
+	if auth(newFakeSSHContext("alice"), "wrong", "") {
+		t.Fatal("wrong password accepted")
+	}
+	if _, ok := userRepo.updated[1]; ok {
+		t.Fatal("password rehashed after failed login")
+	}
+	ctx := newFakeSSHContext("alice")
+	if !auth(ctx, "secret", "") {
+		t.Fatal("legacy password rejected")
+	}
+	hashed, ok := userRepo.updated[1]
+	if !ok || !utils.IsHashedPassword(hashed) {
+		t.Fatalf("stored password %q, want bcrypt hash", hashed)
+	}
+	if ok, legacy := verifyPassword(hashed, "secret"); !ok || legacy {
+		t.Errorf("rehashed password verify = (%v, %v)", ok, legacy)
+	}
+	if user, _ := ctx.Value(ContextKeyUser).(*entity.User); user == nil || user.Password != hashed {
+		t.Errorf("context user keeps the legacy password")
+	}
+}
@@ -0,0 +1,83 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+type AccessRequestRepo interface {
+	CreateAccessRequest(ctx context.Context, req *AccessRequest) error
+	GetAccessRequest(ctx context.Context, id uint) (*AccessRequest, error)
+	// ReviewAccessRequest 仅更新处于待审批状态的申请, 返回是否更新成功
+	ReviewAccessRequest(ctx context.Context, id uint, values map[string]interface{}) (bool, error)
+	UpdateAccessRequest(ctx context.Context, id uint, values map[string]interface{}) error
+	// ApproveAccessRequest 在同一事务中先创建授权规则, 再更新处于待审批状态的申请, 返回是否更新成功
+	ApproveAccessRequest(ctx context.Context, id uint, values map[string]interface{}, perm *AssetPermission, userIDs []uint) (bool, error)
+	ListAccessRequestsWithPager(ctx context.Context, param *AccessRequestPaginationParam) ([]*AccessRequest, int, error)
+}
+
+const (
+	AccessRequestKindAsset   = "asset"
+	AccessRequestKindCommand = "command" // 命令过滤规则要求审批的命令
+)
+
+const (
+	AccessRequestPending  = "pending"
+	AccessRequestApproved = "approved"
+	AccessRequestRejected = "rejected"
+	AccessRequestCanceled = "canceled"
+)
+
+// AccessRequest 用户在终端发起的临时访问申请, 审批通过后生成有时效的授权规则;
+// 命令审批类申请审批通过后仅放行对应会话中的该条命令
+type AccessRequest struct {
+	BaseModel
+	Kind          string     `json:"kind" gorm:"type:varchar(16);not null;default:asset;index"`
+	UserRef       uint       `json:"user_id" gorm:"not null;index"`
+	User          *User      `json:"user,omitempty" gorm:"foreignKey:UserRef"`
+	ClusterName   string     `json:"cluster_name" gorm:"type:varchar(128);not null"`
+	Namespace     string     `json:"namespace" gorm:"type:varchar(256);not null"`
+	PodName       string     `json:"pod_name" gorm:"type:varchar(256)"` // 为空表示申请整个命名空间
+	Reason        string     `json:"reason" gorm:"type:varchar(512)"`
+	Duration      int        `json:"duration"` // 申请时长 (单位: 分钟)
+	Status        string     `json:"status" gorm:"type:varchar(16);not null;index"`
+	Reviewer      string     `json:"reviewer" gorm:"type:varchar(128)"`
+	ReviewComment string     `json:"review_comment" gorm:"type:varchar(512)"`
+	DateReviewed  *time.Time `json:"date_reviewed"`
+	PermissionRef uint       `json:"permission_id"`
+	SessionID     string     `json:"session_id" gorm:"type:varchar(36)"`
+	Command       string     `json:"command" gorm:"type:text"`
+}
+
+func (r *AccessRequest) TableName() string {
+	return "access_requests"
+}
+
+func (r *AccessRequest) Target() string {
+	if r.PodName == "" {
+		return fmt.Sprintf("%s/%s", r.ClusterName, r.Namespace)
+	}
+	return fmt.Sprintf("%s/%s/%s", r.ClusterName, r.Namespace, r.PodName)
+}
+
+type AccessRequestPaginationParam struct {
+	PageNo   int    `form:"page_no"`
+	PageSize int    `form:"page_size"`
+	Status   string `form:"status"`
+	Kind     string `form:"kind"`
+	UserID   uint   `form:"user_id"`
+}
+
+type AccessRequestListResponse struct {
+	Total int              `json:"total"`
+	Data  []*AccessRequest `json:"data"`
+}
+
+// AccessRequestReviewReq 审批人取自通过认证的调用者, 不接受请求体中的值
+type AccessRequestReviewReq struct {
+	Reviewer   string `json:"-"`
+	ReviewerID uint   `json:"-"`
+	Comment    string `json:"comment"`
+	Duration   int    `json:"duration" binding:"omitempty,min=1"` // 审批时可以调整授权时长 (单位: 分钟)
+}
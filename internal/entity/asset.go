@@ -14,6 +14,7 @@ type Asset struct {
 	PodName     string
 	PodIP       string
 	PodStatus   string
+	Labels      map[string]string
 	Cluster     *ClusterConfig
 }
 
@@ -0,0 +1,50 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+type CommandRepo interface {
+	CreateCommand(ctx context.Context, cmd *Command) error
+	ListCommandsWithPager(ctx context.Context, param *CommandPaginationParam) ([]*Command, int, error)
+}
+
+// Command 会话中用户执行的命令审计记录
+type Command struct {
+	BaseModel
+	SessionID   string `json:"session_id" gorm:"type:varchar(36);not null;index"`
+	UserRef     uint   `json:"user_id" gorm:"index"`
+	User        string `json:"user" gorm:"type:varchar(128);not null;index"`
+	ClusterName string `json:"cluster_name" gorm:"type:varchar(128)"`
+	Namespace   string `json:"namespace" gorm:"type:varchar(256)"`
+	PodName     string `json:"pod_name" gorm:"type:varchar(256)"`
+	Input       string `json:"input" gorm:"type:text"`
+	Output      string `json:"output" gorm:"type:text"` // 命令输出摘要
+	// FilterAction 命中的命令过滤规则动作, 未命中时为空
+	FilterAction string    `json:"filter_action" gorm:"type:varchar(16);index"`
+	Timestamp    time.Time `json:"timestamp" gorm:"index"`
+}
+
+func (c *Command) TableName() string {
+	return "commands"
+}
+
+type CommandPaginationParam struct {
+	PageNo    int        `form:"page_no"`
+	PageSize  int        `form:"page_size"`
+	SessionID string     `form:"session_id"`
+	User      string     `form:"user"`
+	Cluster   string     `form:"cluster"`
+	Namespace string     `form:"namespace"`
+	PodName   string     `form:"pod_name"`
+	Search    string     `form:"search"` // 按命令内容模糊查询
+	Action    string     `form:"filter_action"`
+	DateFrom  *time.Time `form:"date_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	DateTo    *time.Time `form:"date_to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type CommandListResponse struct {
+	Total int        `json:"total"`
+	Data  []*Command `json:"data"`
+}
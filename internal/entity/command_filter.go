@@ -0,0 +1,158 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+type CommandFilterRepo interface {
+	CreateCommandFilter(ctx context.Context, acl *CommandFilterACL, userIDs, groupIDs []uint) error
+	UpdateCommandFilter(ctx context.Context, aclID uint, values map[string]interface{}, userIDs, groupIDs []uint) error
+	DeleteCommandFilter(ctx context.Context, aclID uint) error
+	GetCommandFilter(ctx context.Context, aclID uint) (*CommandFilterACL, error)
+	ListCommandFilters(ctx context.Context) ([]*CommandFilterACL, error)
+	// ListUserCommandFilters 查询对用户生效的规则, 未指定用户和用户组的规则对所有用户生效
+	ListUserCommandFilters(ctx context.Context, userID uint) ([]*CommandFilterACL, error)
+}
+
+const (
+	CommandFilterTypeRegex   = "regex"
+	CommandFilterTypeCommand = "command"
+)
+
+const (
+	CommandFilterAccept = "accept"
+	CommandFilterDeny   = "deny"
+	CommandFilterWarn   = "warn"
+	CommandFilterReview = "review"
+)
+
+const DefaultCommandFilterPriority = 50
+
+// CommandFilterACL 命令过滤规则, 按优先级从小到大匹配, 命中第一条规则后执行对应动作
+// Content 每行一条, regex 类型为正则表达式, command 类型为命令关键字
+type CommandFilterACL struct {
+	BaseModel
+	Name        string       `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Priority    int          `json:"priority" gorm:"not null;default:50"`
+	Users       []*User      `json:"users,omitempty" gorm:"many2many:command_filter_acl_users"`
+	UserGroups  []*UserGroup `json:"user_groups,omitempty" gorm:"many2many:command_filter_acl_user_groups"`
+	ClusterName string       `json:"cluster_name" gorm:"type:varchar(128)"`
+	Namespace   string       `json:"namespace" gorm:"type:varchar(256)"`
+	Type        string       `json:"type" gorm:"type:varchar(16);not null"`
+	Content     string       `json:"content" gorm:"type:text"`
+	IgnoreCase  bool         `json:"ignore_case"`
+	Action      string       `json:"action" gorm:"type:varchar(16);not null"`
+	IsActive    bool         `json:"is_active"`
+	Comment     string       `json:"comment" gorm:"type:varchar(256)"`
+
+	pattern *regexp.Regexp
+}
+
+func (f *CommandFilterACL) TableName() string {
+	return "command_filter_acls"
+}
+
+func (f *CommandFilterACL) String() string {
+	return fmt.Sprintf("%s(%s)", f.Name, f.Action)
+}
+
+// Compile 将规则内容编译为正则表达式
+func (f *CommandFilterACL) Compile() error {
+	items := make([]string, 0)
+	for _, line := range strings.Split(f.Content, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		switch f.Type {
+		case CommandFilterTypeRegex:
+			if _, err := regexp.Compile(line); err != nil {
+				return fmt.Errorf("invalid regex %q: %w", line, err)
+			}
+			items = append(items, "(?:"+line+")")
+		case CommandFilterTypeCommand:
+			// 关键字前后须为命令分隔符, 避免 kill 1 匹配 kill 10
+			items = append(items, `(?:^|[\s;&|(])`+regexp.QuoteMeta(line)+`(?:$|[\s;&|)])`)
+		default:
+			return fmt.Errorf("unsupported command filter type %q", f.Type)
+		}
+	}
+	if len(items) == 0 {
+		return fmt.Errorf("command filter content is empty")
+	}
+	expr := strings.Join(items, "|")
+	if f.IgnoreCase {
+		expr = "(?i)" + expr
+	}
+	pattern, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	f.pattern = pattern
+	return nil
+}
+
+// MatchAsset 集群、命名空间为空表示不限制
+func (f *CommandFilterACL) MatchAsset(asset *Asset) bool {
+	if f.ClusterName != "" && f.ClusterName != asset.ClusterName {
+		return false
+	}
+	if f.Namespace != "" && f.Namespace != asset.Namespace {
+		return false
+	}
+	return true
+}
+
+func (f *CommandFilterACL) Match(command string) bool {
+	return f.pattern != nil && f.pattern.MatchString(command)
+}
+
+type CommandFilterACLs []*CommandFilterACL
+
+func (acls CommandFilterACLs) Sort() {
+	sort.SliceStable(acls, func(i, j int) bool {
+		if acls[i].Priority != acls[j].Priority {
+			return acls[i].Priority < acls[j].Priority
+		}
+		return acls[i].ID < acls[j].ID
+	})
+}
+
+// Match 返回第一条匹配的规则, 未匹配时返回 nil
+func (acls CommandFilterACLs) Match(command string) *CommandFilterACL {
+	for _, acl := range acls {
+		if acl.Match(command) {
+			return acl
+		}
+	}
+	return nil
+}
+
+// Restricted 存在拒绝或复核规则时, 无法识别的命令不能放行
+func (acls CommandFilterACLs) Restricted() bool {
+	for _, acl := range acls {
+		if acl.Action == CommandFilterDeny || acl.Action == CommandFilterReview {
+			return true
+		}
+	}
+	return false
+}
+
+type CommandFilterReq struct {
+	Name        string `json:"name" binding:"required"`
+	Priority    int    `json:"priority" binding:"omitempty,min=1,max=100"`
+	Users       []uint `json:"users"`
+	UserGroups  []uint `json:"user_groups"`
+	ClusterName string `json:"cluster_name"`
+	Namespace   string `json:"namespace"`
+	Type        string `json:"type" binding:"required,oneof=regex command"`
+	Content     string `json:"content" binding:"required"`
+	IgnoreCase  bool   `json:"ignore_case"`
+	Action      string `json:"action" binding:"required,oneof=accept deny warn review"`
+	IsActive    *bool  `json:"is_active"`
+	Comment     string `json:"comment"`
+}
@@ -1,9 +1,22 @@
 package entity
 
-import "time"
+import (
+	"math"
+	"time"
+)
 
 type ExpireInfo int64
 
+// ExpireNever 不会过期, 用于管理员或未设置截止时间的授权
+const ExpireNever ExpireInfo = math.MaxInt64
+
+func NewExpireInfo(t *time.Time) ExpireInfo {
+	if t == nil {
+		return ExpireNever
+	}
+	return ExpireInfo(t.Unix())
+}
+
 func (e ExpireInfo) IsExpired(now time.Time) bool {
 	return int64(e) < now.Unix()
 }
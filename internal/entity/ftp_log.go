@@ -0,0 +1,63 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+type FTPLogRepo interface {
+	CreateFTPLog(ctx context.Context, log *FTPLog) error
+	ListFTPLogsWithPager(ctx context.Context, param *FTPLogPaginationParam) ([]*FTPLog, int, error)
+}
+
+const (
+	FTPOperateUpload   = "upload"
+	FTPOperateDownload = "download"
+	FTPOperateRename   = "rename"
+	FTPOperateDelete   = "delete"
+	FTPOperateMkdir    = "mkdir"
+	FTPOperateRmdir    = "rmdir"
+)
+
+// FTPLog 用户通过 SFTP 或会话内 rz/sz 操作容器内文件的审计记录
+type FTPLog struct {
+	BaseModel
+	SessionID   string    `json:"session_id" gorm:"type:varchar(36);index"` // rz/sz 传输所在的会话, SFTP 操作为空
+	UserRef     uint      `json:"user_id" gorm:"index"`
+	User        string    `json:"user" gorm:"type:varchar(128);not null;index"`
+	RemoteAddr  string    `json:"remote_addr" gorm:"type:varchar(128)"`
+	ClusterName string    `json:"cluster_name" gorm:"type:varchar(128)"`
+	Namespace   string    `json:"namespace" gorm:"type:varchar(256)"`
+	PodName     string    `json:"pod_name" gorm:"type:varchar(256)"`
+	Container   string    `json:"container" gorm:"type:varchar(63)"`
+	Operate     string    `json:"operate" gorm:"type:varchar(16);index"`
+	Path        string    `json:"path" gorm:"type:varchar(1024)"`   // 容器内文件路径
+	Target      string    `json:"target" gorm:"type:varchar(1024)"` // 重命名的目标路径
+	Filesize    int64     `json:"filesize"`                         // 上传、下载的文件大小 (单位: 字节)
+	IsSuccess   bool      `json:"is_success"`
+	Message     string    `json:"message" gorm:"type:varchar(512)"` // 失败原因
+	Timestamp   time.Time `json:"timestamp" gorm:"index"`
+}
+
+func (l *FTPLog) TableName() string {
+	return "ftp_logs"
+}
+
+type FTPLogPaginationParam struct {
+	PageNo    int        `form:"page_no"`
+	PageSize  int        `form:"page_size"`
+	SessionID string     `form:"session_id"`
+	User      string     `form:"user"`
+	Cluster   string     `form:"cluster"`
+	Namespace string     `form:"namespace"`
+	PodName   string     `form:"pod_name"`
+	Operate   string     `form:"operate"`
+	Search    string     `form:"search"` // 按文件路径模糊查询
+	DateFrom  *time.Time `form:"date_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	DateTo    *time.Time `form:"date_to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type FTPLogListResponse struct {
+	Total int       `json:"total"`
+	Data  []*FTPLog `json:"data"`
+}
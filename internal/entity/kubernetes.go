@@ -91,20 +91,22 @@ type PodRepo interface {
 	CreateOrUpdatePod(_ context.Context, pod *Pod) error
 	DeletePodByNameAndNamespace(_ context.Context, name, ns string, key string) error
 	ListPodsWithPreLoadCluster(_ context.Context, filter *Pod, sortBy string) ([]*Pod, error)
-	PreloadPodsWithPager(_ context.Context, filter *Pod, reqParam *PaginationParam) ([]*Pod, int, error)
+	PreloadPodsWithPager(_ context.Context, filter *Pod, reqParam *PaginationParam, perms []*AssetPermission) ([]*Pod, int, error)
 	//CountPods(_ context.Context, filter *Pod, reqParam *PaginationParam) (int, error)
+	ListPodContainers(_ context.Context, pod *Pod) ([]*Container, error)
 }
 
 type Pod struct {
 	BaseModel
-	PodName    string         `gorm:"not null;type:varchar(256);uniqueIndex:idx_namespace_pod_name_cluster_ref"`
-	Namespace  string         `gorm:"not null;type:varchar(256);uniqueIndex:idx_namespace_pod_name_cluster_ref"`
-	PodIP      string         `gorm:"pod_ip;varchar(15)"`
-	Status     string         `gorm:"type:varchar(28);not null"`
-	ClusterRef string         `gorm:"not null;uniqueIndex:idx_namespace_pod_name_cluster_ref"`
-	Cluster    *ClusterConfig `gorm:"foreignKey:ClusterRef;references:UniqKey"`
-	//Containers   []*Container   `gorm:"foreignKey:PodRef"`
-	ResourceKind string `gorm:"-"`
+	PodName      string         `gorm:"not null;type:varchar(256);uniqueIndex:idx_namespace_pod_name_cluster_ref"`
+	Namespace    string         `gorm:"not null;type:varchar(256);uniqueIndex:idx_namespace_pod_name_cluster_ref"`
+	PodIP        string         `gorm:"pod_ip;varchar(15)"`
+	Status       string         `gorm:"type:varchar(28);not null"`
+	Labels       string         `gorm:"type:text"` // 编码格式见 EncodePodLabels
+	ClusterRef   string         `gorm:"not null;uniqueIndex:idx_namespace_pod_name_cluster_ref"`
+	Cluster      *ClusterConfig `gorm:"foreignKey:ClusterRef;references:UniqKey"`
+	Containers   []*Container   `gorm:"-"` // 随 pod 事件同步, 单独存储在 container_info
+	ResourceKind string         `gorm:"-"`
 }
 
 func (c *Pod) TableName() string {
@@ -113,10 +115,13 @@ func (c *Pod) TableName() string {
 
 type Container struct {
 	BaseModel
-	ContainerName string `gorm:"not null;type:varchar(256)"`
-	ContainerIP   string `gorm:"not null;type:varchar(256)"`
+	ContainerName string `gorm:"not null;type:varchar(63);uniqueIndex:idx_container_pod_name_cluster_ref"`
+	PodName       string `gorm:"not null;type:varchar(253);uniqueIndex:idx_container_pod_name_cluster_ref"`
+	Namespace     string `gorm:"not null;type:varchar(63);uniqueIndex:idx_container_pod_name_cluster_ref"`
+	ClusterRef    string `gorm:"not null;type:varchar(255);uniqueIndex:idx_container_pod_name_cluster_ref"`
+	Image         string `gorm:"type:varchar(512)"`
 	Status        string `gorm:"not null;type:varchar(28)"`
-	PodRef        uint
+	Ready         bool   `gorm:"type:boolean"`
 }
 
 func (c *Container) TableName() string {
@@ -0,0 +1,18 @@
+package entity
+
+import (
+	"context"
+	"time"
+)
+
+// LoginBlockRepo 记录登录失败次数以及锁定状态
+type LoginBlockRepo interface {
+	IncrFailedCount(ctx context.Context, key string, window time.Duration) (int, error)
+	ClearFailedCount(ctx context.Context, key string)
+	Block(ctx context.Context, key string, duration time.Duration)
+	IsBlocked(ctx context.Context, key string) bool
+	Unblock(ctx context.Context, key string)
+	// AddFailedSource 记录 key 登录失败的来源, PopFailedSources 取出并清除记录
+	AddFailedSource(ctx context.Context, key, source string, expire time.Duration)
+	PopFailedSources(ctx context.Context, key string) []string
+}
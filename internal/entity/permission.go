@@ -0,0 +1,186 @@
+package entity
+
+import (
+	"context"
+	"fmt"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/selection"
+	"sort"
+	"strings"
+	"time"
+)
+
+type UserGroupRepo interface {
+	CreateUserGroup(ctx context.Context, group *UserGroup, userIDs []uint) error
+	UpdateUserGroup(ctx context.Context, groupID uint, values map[string]interface{}, userIDs []uint) error
+	DeleteUserGroup(ctx context.Context, groupID uint) error
+	GetUserGroup(ctx context.Context, groupID uint) (*UserGroup, error)
+	ListUserGroups(ctx context.Context) ([]*UserGroup, error)
+	CountUserGroupsByIDs(ctx context.Context, ids []uint) (int, error)
+}
+
+// UserGroup 用户组, 授权规则可以直接授予用户组
+type UserGroup struct {
+	BaseModel
+	Name    string  `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Comment string  `json:"comment" gorm:"type:varchar(256)"`
+	Users   []*User `json:"users,omitempty" gorm:"many2many:user_group_members"`
+}
+
+func (g *UserGroup) TableName() string {
+	return "user_groups"
+}
+
+type AssetPermissionRepo interface {
+	CreatePermission(ctx context.Context, perm *AssetPermission, userIDs, groupIDs []uint) error
+	UpdatePermission(ctx context.Context, permID uint, values map[string]interface{}, userIDs, groupIDs []uint) error
+	DeletePermission(ctx context.Context, permID uint) error
+	GetPermission(ctx context.Context, permID uint) (*AssetPermission, error)
+	ListPermissions(ctx context.Context) ([]*AssetPermission, error)
+	ListUserPermissions(ctx context.Context, userID uint) ([]*AssetPermission, error)
+}
+
+// AssetPermission 资产授权规则, 集群、命名空间、Pod 名称为空表示不限制, PodSelector 为 k8s label selector
+type AssetPermission struct {
+	BaseModel
+	Name          string       `json:"name" gorm:"type:varchar(128);not null;uniqueIndex"`
+	Users         []*User      `json:"users,omitempty" gorm:"many2many:asset_permission_users"`
+	UserGroups    []*UserGroup `json:"user_groups,omitempty" gorm:"many2many:asset_permission_user_groups"`
+	ClusterName   string       `json:"cluster_name" gorm:"type:varchar(128)"`
+	Namespace     string       `json:"namespace" gorm:"type:varchar(256)"`
+	PodName       string       `json:"pod_name" gorm:"type:varchar(256)"`
+	PodSelector   string       `json:"pod_selector" gorm:"type:varchar(512)"`
+	IsActive      bool         `json:"is_active"`
+	ReadOnly      bool         `json:"read_only"`      // 只读授权, 只能查看会话输出
+	AllowUpload   bool         `json:"allow_upload"`   // 允许通过 SFTP 上传及修改容器内文件, 只读授权时无效
+	AllowDownload bool         `json:"allow_download"` // 允许通过 SFTP 下载容器内文件
+	DateStart     *time.Time   `json:"date_start"`     // 为空表示立即生效
+	DateExpired   *time.Time   `json:"date_expired"`   // 为空表示永不过期
+	Comment       string       `json:"comment" gorm:"type:varchar(256)"`
+}
+
+func (p *AssetPermission) TableName() string {
+	return "asset_permissions"
+}
+
+func (p *AssetPermission) String() string {
+	return fmt.Sprintf("%s(%s/%s/%s/%s)", p.Name, p.ClusterName, p.Namespace, p.PodName, p.PodSelector)
+}
+
+// LabelRequirements 解析 PodSelector, 仅支持可以转换为 SQL 查询的操作符
+func (p *AssetPermission) LabelRequirements() (labels.Requirements, error) {
+	if strings.TrimSpace(p.PodSelector) == "" {
+		return nil, nil
+	}
+	selector, err := labels.Parse(p.PodSelector)
+	if err != nil {
+		return nil, err
+	}
+	requirements, _ := selector.Requirements()
+	for _, r := range requirements {
+		switch r.Operator() {
+		case selection.GreaterThan, selection.LessThan:
+			return nil, fmt.Errorf("unsupported pod selector operator %s", r.Operator())
+		}
+	}
+	return requirements, nil
+}
+
+// IsValid 授权规则已启用且处于有效期内
+func (p *AssetPermission) IsValid(now time.Time) bool {
+	if !p.IsActive {
+		return false
+	}
+	if p.DateStart != nil && now.Before(*p.DateStart) {
+		return false
+	}
+	if p.DateExpired != nil && !now.Before(*p.DateExpired) {
+		return false
+	}
+	return true
+}
+
+func (p *AssetPermission) ExpireAt() ExpireInfo {
+	return NewExpireInfo(p.DateExpired)
+}
+
+// MatchAsset 判断资产是否在授权范围内
+func (p *AssetPermission) MatchAsset(asset *Asset) bool {
+	if !p.IsValid(time.Now()) {
+		return false
+	}
+	if p.ClusterName != "" && p.ClusterName != asset.ClusterName {
+		return false
+	}
+	if p.Namespace != "" && p.Namespace != asset.Namespace {
+		return false
+	}
+	if p.PodName != "" && p.PodName != asset.PodName {
+		return false
+	}
+	requirements, err := p.LabelRequirements()
+	if err != nil {
+		return false
+	}
+	set := labels.Set(asset.Labels)
+	for _, r := range requirements {
+		if !r.Matches(set) {
+			return false
+		}
+	}
+	return true
+}
+
+type UserGroupReq struct {
+	Name    string `json:"name" binding:"required"`
+	Comment string `json:"comment"`
+	Users   []uint `json:"users"`
+}
+
+type AssetPermissionReq struct {
+	Name          string     `json:"name" binding:"required"`
+	Users         []uint     `json:"users"`
+	UserGroups    []uint     `json:"user_groups"`
+	ClusterName   string     `json:"cluster_name"`
+	Namespace     string     `json:"namespace"`
+	PodName       string     `json:"pod_name"`
+	PodSelector   string     `json:"pod_selector"`
+	IsActive      *bool      `json:"is_active"`
+	ReadOnly      bool       `json:"read_only"`
+	AllowUpload   bool       `json:"allow_upload"`
+	AllowDownload bool       `json:"allow_download"`
+	DateStart     *time.Time `json:"date_start"`
+	DateExpired   *time.Time `json:"date_expired"`
+	Comment       string     `json:"comment"`
+}
+
+// EncodePodLabels 将 labels 按 key 排序后编码为 ",k1=v1,k2=v2," 便于使用 like 查询
+func EncodePodLabels(podLabels map[string]string) string {
+	if len(podLabels) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(podLabels))
+	for k := range podLabels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var b strings.Builder
+	b.WriteString(",")
+	for _, k := range keys {
+		b.WriteString(k)
+		b.WriteString("=")
+		b.WriteString(podLabels[k])
+		b.WriteString(",")
+	}
+	return b.String()
+}
+
+func DecodePodLabels(encoded string) map[string]string {
+	podLabels := make(map[string]string)
+	for _, item := range strings.Split(strings.Trim(encoded, ","), ",") {
+		if kv := strings.SplitN(item, "=", 2); len(kv) == 2 {
+			podLabels[kv[0]] = kv[1]
+		}
+	}
+	return podLabels
+}
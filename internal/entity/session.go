@@ -1,6 +1,10 @@
 package entity
 
-import "encoding/json"
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
 
 type LabelField string
 
@@ -20,24 +24,85 @@ func (s *LabelField) UnmarshalJSON(bytes []byte) error {
 	return nil
 }
 
+type SessionRepo interface {
+	CreateSession(ctx context.Context, sess *Session) error
+	FinishSession(ctx context.Context, sess *Session) error
+	ListSessionsWithPager(ctx context.Context, param *SessionPaginationParam) ([]*Session, int, error)
+}
+
+// Session 在线会话信息, 同时作为会话历史持久化
 type Session struct {
-	ID         string     `json:"id,omitempty"`
-	User       string     `json:"user"`
-	Asset      string     `json:"asset"`
-	Account    string     `json:"account"`
-	LoginFrom  LabelField `json:"login_from,omitempty"`
-	RemoteAddr string     `json:"remote_addr"`
-	Protocol   string     `json:"protocol"`
-	//DateStart  common.UTCTime `json:"date_start"`
-	UserID    int        `json:"user_id"`
-	AssetID   int        `json:"asset_id"`
-	AccountID string     `json:"account_id"`
-	Type      LabelField `json:"type"`
+	ID           string     `json:"id,omitempty" gorm:"type:varchar(36);primaryKey"`
+	User         string     `json:"user" gorm:"type:varchar(128);not null;index"`
+	Asset        string     `json:"asset" gorm:"type:varchar(256)"`
+	ClusterName  string     `json:"cluster_name" gorm:"type:varchar(128);index"`
+	Namespace    string     `json:"namespace" gorm:"type:varchar(256)"`
+	PodName      string     `json:"pod_name" gorm:"type:varchar(256)"`
+	Container    string     `json:"container" gorm:"type:varchar(256)"`
+	Account      string     `json:"account" gorm:"-"`
+	LoginFrom    LabelField `json:"login_from,omitempty" gorm:"type:varchar(16)"`
+	RemoteAddr   string     `json:"remote_addr" gorm:"type:varchar(128)"`
+	Protocol     string     `json:"protocol" gorm:"-"`
+	DateStart    time.Time  `json:"date_start" gorm:"index"`
+	DateEnd      *time.Time `json:"date_end"`
+	ExitReason   string     `json:"exit_reason" gorm:"type:varchar(32);index"`
+	TerminatedBy string     `json:"terminated_by" gorm:"type:varchar(128)"`
+	UserID       int        `json:"user_id" gorm:"index"`
+	AssetID      int        `json:"asset_id"`
+	AccountID    string     `json:"account_id" gorm:"-"`
+	Type         LabelField `json:"type" gorm:"type:varchar(16)"`
+	ShareMode    string     `json:"share_mode" gorm:"type:varchar(8)"`
+}
+
+func (s *Session) TableName() string {
+	return "sessions"
 }
 
+// 会话结束原因
+const (
+	ExitReasonNormal         = "normal"
+	ExitReasonIdle           = "idle"
+	ExitReasonMaxTime        = "max_time"
+	ExitReasonAdminTerminate = "admin_terminate"
+	ExitReasonExpired        = "permission_expired"
+)
+
+type SessionPaginationParam struct {
+	PageNo       int        `form:"page_no"`
+	PageSize     int        `form:"page_size"`
+	User         string     `form:"user"`
+	Cluster      string     `form:"cluster"`
+	Namespace    string     `form:"namespace"`
+	PodName      string     `form:"pod_name"`
+	RemoteAddr   string     `form:"remote_addr"`
+	ExitReason   string     `form:"exit_reason"`
+	TerminatedBy string     `form:"terminated_by"`
+	DateFrom     *time.Time `form:"date_from" time_format:"2006-01-02T15:04:05Z07:00"`
+	DateTo       *time.Time `form:"date_to" time_format:"2006-01-02T15:04:05Z07:00"`
+}
+
+type SessionListResponse struct {
+	Total int        `json:"total"`
+	Data  []*Session `json:"data"`
+}
+
+// SessionTaskReq 管理员对在线会话下发的任务
+type SessionTaskReq struct {
+	Name     string `json:"name" binding:"required,oneof=kill_session lock_session unlock_session"`
+	Operator string `json:"operator" binding:"required"`
+}
+
+// 会话共享模式, 由会话发起人在菜单中设置
+const (
+	ShareModeOff      = "off"
+	ShareModeReadOnly = "ro"
+	ShareModeWritable = "rw"
+)
+
 const (
 	NORMALType  LabelField = "normal"
 	TUNNELType  LabelField = "tunnel"
 	COMMANDType LabelField = "command"
 	SFTPType    LabelField = "sftp"
+	LOGSType    LabelField = "logs"
 )
@@ -1,12 +1,12 @@
 package entity
 
 type TerminalConfig struct {
-	AssetListPageSize string `json:"TERMINAL_ASSET_LIST_PAGE_SIZE"`
-	AssetListSortBy   string `json:"TERMINAL_ASSET_LIST_SORT_BY"`
-	HeaderTitle       string `json:"TERMINAL_HEADER_TITLE"`
-	PasswordAuth      bool   `json:"TERMINAL_PASSWORD_AUTH"`
-	PublicKeyAuth     bool   `json:"TERMINAL_PUBLIC_KEY_AUTH"`
-	//ReplayStorage       ReplayConfig           `json:"TERMINAL_REPLAY_STORAGE"`
+	AssetListPageSize   string                 `json:"TERMINAL_ASSET_LIST_PAGE_SIZE"`
+	AssetListSortBy     string                 `json:"TERMINAL_ASSET_LIST_SORT_BY"`
+	HeaderTitle         string                 `json:"TERMINAL_HEADER_TITLE"`
+	PasswordAuth        bool                   `json:"TERMINAL_PASSWORD_AUTH"`
+	PublicKeyAuth       bool                   `json:"TERMINAL_PUBLIC_KEY_AUTH"`
+	ReplayStorage       ReplayConfig           `json:"TERMINAL_REPLAY_STORAGE"`
 	CommandStorage      map[string]interface{} `json:"TERMINAL_COMMAND_STORAGE"`
 	SessionKeepDuration int                    `json:"TERMINAL_SESSION_KEEP_DURATION"`
 	TelnetRegex         string                 `json:"TERMINAL_TELNET_REGEX"`
@@ -17,6 +17,31 @@ type TerminalConfig struct {
 	EnableSessionShare  bool                   `json:"SECURITY_SESSION_SHARE"`
 }
 
+const (
+	ReplayStorageLocal = "local"
+	ReplayStorageS3    = "s3"
+	ReplayStorageHTTP  = "http"
+	ReplayStorageNull  = "null" // 不录制会话
+)
+
+type ReplayConfig struct {
+	TypeName string `json:"TYPE" mapstructure:"type"`
+
+	// local: 归档目录, 为空时保留在录像目录中
+	RootPath string `json:"ROOT_PATH" mapstructure:"root_path"`
+
+	// s3
+	Endpoint  string `json:"ENDPOINT" mapstructure:"endpoint"`
+	Region    string `json:"REGION" mapstructure:"region"`
+	Bucket    string `json:"BUCKET" mapstructure:"bucket"`
+	AccessKey string `json:"ACCESS_KEY" mapstructure:"access_key"`
+	SecretKey string `json:"SECRET_KEY" mapstructure:"secret_key"`
+
+	// http: PUT <URL>/<date>/<session id>.cast.gz
+	URL     string            `json:"URL" mapstructure:"url"`
+	Headers map[string]string `json:"HEADERS" mapstructure:"headers"`
+}
+
 type TaskKwargs struct {
 	TerminatedBy  string `json:"terminated_by"`
 	CreatedByUser string `json:"created_by"`
@@ -3,11 +3,22 @@ package entity
 import (
 	"context"
 	"fmt"
+	"golang.org/x/crypto/ssh"
+	"gorm.io/gorm"
+	"strings"
 )
 
 type UserRepo interface {
 	GetInfoByID(ctx context.Context, filter *User) (*User, error)
 	GetInfoByName(ctx context.Context, username string, user *User) error
+	ListPublicKeys(ctx context.Context, userID uint) ([]*UserPublicKey, error)
+	UpdatePassword(ctx context.Context, userID uint, hashedPassword string) error
+	UpdateOTPSecret(ctx context.Context, userID uint, secret string) error
+	CreateUser(ctx context.Context, user *User) error
+	UpdateUser(ctx context.Context, userID uint, values map[string]interface{}) error
+	ReplacePublicKeys(ctx context.Context, userID uint, keys []*UserPublicKey) error
+	ListUsersWithPager(ctx context.Context, param *UserPaginationParam) ([]*User, int, error)
+	CountUsersByIDs(ctx context.Context, ids []uint) (int, error)
 }
 
 type User struct {
@@ -16,11 +27,13 @@ type User struct {
 	Name     string `json:"name"`
 	Username string `json:"username"`
 	Email    string `json:"email"`
-	Password string `json:"password"`
-	//Role     string `json:"role"`
+	Password string `json:"-"` // bcrypt 哈希
+	Role     string `json:"role" gorm:"type:varchar(32);default:user"`
 	//IsValid  bool   `json:"is_valid"`
-	IsActive bool `json:"is_active"`
-	//OTPLevel int    `json:"otp_level"`
+	IsActive     bool             `json:"is_active"`
+	OTPLevel     int              `json:"otp_level" gorm:"default:0"` // 0 未开启 MFA, 1 开启 MFA
+	OTPSecretKey string           `json:"-" gorm:"type:varchar(64)"`
+	PublicKeys   []*UserPublicKey `json:"public_keys,omitempty" gorm:"foreignKey:UserRef"`
 }
 
 func (u *User) String() string {
@@ -31,6 +44,91 @@ func (u *User) TableName() string {
 	return "users"
 }
 
+const (
+	OTPLevelDisabled = 0
+	OTPLevelEnabled  = 1
+)
+
+const (
+	RoleAdmin   = "admin" // 不受资产授权限制
+	RoleUser    = "user"
+	RoleAuditor = "auditor" // 审计员, 只能以只读模式进入会话
+)
+
+func (u *User) IsAdmin() bool {
+	return u.Role == RoleAdmin
+}
+
+func (u *User) IsAuditor() bool {
+	return u.Role == RoleAuditor
+}
+
+func (u *User) MFAEnabled() bool {
+	return u.OTPLevel >= OTPLevelEnabled
+}
+
+// UserPublicKey 用户登录使用的 ssh 公钥, 内容与 authorized_keys 的单行格式一致
+type UserPublicKey struct {
+	BaseModel
+	UserRef     uint   `json:"user_id" gorm:"not null;index"`
+	Name        string `json:"name" gorm:"type:varchar(128)"`
+	PublicKey   string `json:"public_key" gorm:"type:text;not null" binding:"required"`
+	Fingerprint string `json:"fingerprint" gorm:"type:varchar(128);not null;uniqueIndex"`
+}
+
+func (k *UserPublicKey) TableName() string {
+	return "user_public_keys"
+}
+
+// BeforeSave 根据公钥内容计算指纹, 保证指纹与公钥一致
+func (k *UserPublicKey) BeforeSave(_ *gorm.DB) error {
+	pubKey, comment, _, _, err := ssh.ParseAuthorizedKey([]byte(strings.TrimSpace(k.PublicKey)))
+	if err != nil {
+		return fmt.Errorf("invalid public key: %w", err)
+	}
+	if k.Name == "" {
+		k.Name = comment
+	}
+	k.Fingerprint = ssh.FingerprintSHA256(pubKey)
+	return nil
+}
+
+type UserCreateReq struct {
+	Name       string   `json:"name" binding:"required"`
+	Username   string   `json:"username" binding:"required"`
+	Email      string   `json:"email"`
+	Password   string   `json:"password" binding:"required,min=8"`
+	Role       string   `json:"role" binding:"omitempty,oneof=admin user auditor"`
+	IsActive   *bool    `json:"is_active"`
+	OTPLevel   int      `json:"otp_level"`
+	PublicKeys []string `json:"public_keys"`
+}
+
+// UserUpdateReq 只更新请求中携带的字段, public_keys 非空时整体替换用户公钥
+type UserUpdateReq struct {
+	Name       *string  `json:"name"`
+	Email      *string  `json:"email"`
+	Role       *string  `json:"role" binding:"omitempty,oneof=admin user auditor"`
+	IsActive   *bool    `json:"is_active"`
+	OTPLevel   *int     `json:"otp_level"`
+	PublicKeys []string `json:"public_keys"`
+}
+
+type UserResetPasswordReq struct {
+	Password string `json:"password" binding:"required,min=8"`
+}
+
+type UserPaginationParam struct {
+	PageNo   int    `form:"page_no"`
+	PageSize int    `form:"page_size"`
+	Search   string `form:"search"`
+}
+
+type UserListResponse struct {
+	Total int     `json:"total"`
+	Data  []*User `json:"data"`
+}
+
 type ConnectTokenInfo struct {
 	Id     string `json:"id"`
 	Secret string `json:"secret"`
@@ -11,6 +11,9 @@ type ConnectInfo struct {
 	//Domain   *Domain    `json:"domain"`
 	//Gateway  *Gateway   `json:"gateway"`
 	ExpireAt ExpireInfo `json:"expire_at"`
+	ReadOnly bool       `json:"read_only"` // 只读会话丢弃用户输入
+	Upload   bool       `json:"upload"`    // 允许在会话中使用 rz 上传文件
+	Download bool       `json:"download"`  // 允许在会话中使用 sz 下载文件
 	//OrgId    string     `json:"org_id"`
 	//OrgName  string     `json:"org_name"`
 	//Platform Platform   `json:"platform"`
@@ -31,3 +34,11 @@ type ConnectOptions struct {
 	DisableAutoHash  *bool   `json:"disableautohash,omitempty"`
 	BackspaceAsCtrlH *bool   `json:"backspaceAsCtrlH,omitempty"`
 }
+
+// AssetAccess 用户对资产的访问权限
+type AssetAccess struct {
+	ExpireAt ExpireInfo
+	ReadOnly bool
+	Upload   bool // 允许上传及修改容器内文件
+	Download bool // 允许下载容器内文件
+}
@@ -15,8 +15,17 @@ func autoMigrate(db *gorm.DB) (err error) {
 		AutoMigrate(
 			&entity.ClusterConfig{},
 			&entity.User{},
+			&entity.UserPublicKey{},
 			&entity.Pod{},
+			&entity.Container{},
 			&entity.Namespace{},
+			&entity.UserGroup{},
+			&entity.AssetPermission{},
+			&entity.AccessRequest{},
+			&entity.Command{},
+			&entity.CommandFilterACL{},
+			&entity.Session{},
+			&entity.FTPLog{},
 		)
 	return
 }
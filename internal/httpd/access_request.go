@@ -0,0 +1,61 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) ListAccessRequests(ctx *gin.Context) {
+	param := new(entity.AccessRequestPaginationParam)
+	if err := ctx.ShouldBindQuery(param); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	resp, err := s.jmsService.ListAccessRequests(ctx, param)
+	if err != nil {
+		utils.FailWithMessage(utils.QueryAccessRequestError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(resp, ctx)
+}
+
+func (s *Server) ApproveAccessRequest(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	req := new(entity.AccessRequestReviewReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	reviewer := currentUser(ctx)
+	req.Reviewer, req.ReviewerID = reviewer.Username, reviewer.ID
+	accessReq, err := s.jmsService.ApproveAccessRequest(ctx, id, req)
+	if err != nil {
+		utils.FailWithMessage(utils.ReviewAccessRequestError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(accessReq, ctx)
+}
+
+func (s *Server) RejectAccessRequest(ctx *gin.Context) {
+	id, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	req := new(entity.AccessRequestReviewReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	reviewer := currentUser(ctx)
+	req.Reviewer, req.ReviewerID = reviewer.Username, reviewer.ID
+	accessReq, err := s.jmsService.RejectAccessRequest(ctx, id, req)
+	if err != nil {
+		utils.FailWithMessage(utils.ReviewAccessRequestError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(accessReq, ctx)
+}
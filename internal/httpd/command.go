@@ -0,0 +1,21 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) ListCommands(ctx *gin.Context) {
+	param := new(entity.CommandPaginationParam)
+	if err := ctx.ShouldBindQuery(param); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	resp, err := s.jmsService.ListCommands(ctx, param)
+	if err != nil {
+		utils.FailWithMessage(utils.QueryCommandError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(resp, ctx)
+}
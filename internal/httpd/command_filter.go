@@ -0,0 +1,60 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) ListCommandFilters(ctx *gin.Context) {
+	acls, err := s.permService.ListCommandFilters(ctx)
+	if err != nil {
+		utils.FailWithMessage(utils.QueryCommandFilterError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(acls, ctx)
+}
+
+func (s *Server) CreateCommandFilter(ctx *gin.Context) {
+	req := new(entity.CommandFilterReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	acl, err := s.permService.CreateCommandFilter(ctx, req)
+	if err != nil {
+		utils.FailWithMessage(utils.CreateCommandFilterError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(acl, ctx)
+}
+
+func (s *Server) UpdateCommandFilter(ctx *gin.Context) {
+	aclID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	req := new(entity.CommandFilterReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	acl, err := s.permService.UpdateCommandFilter(ctx, aclID, req)
+	if err != nil {
+		utils.FailWithMessage(utils.UpdateCommandFilterError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(acl, ctx)
+}
+
+func (s *Server) DeleteCommandFilter(ctx *gin.Context) {
+	aclID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	if err := s.permService.DeleteCommandFilter(ctx, aclID); err != nil {
+		utils.FailWithMessage(utils.UpdateCommandFilterError, err.Error(), ctx)
+		return
+	}
+	utils.Ok(ctx)
+}
@@ -0,0 +1,46 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/auth"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+)
+
+const (
+	ctxKeyCurrentUser = "CURRENT_USER"
+
+	otpCodeHeader = "X-OTP-Code"
+)
+
+// AdminAuth 管理接口使用 HTTP Basic 认证, 仅允许 admin 角色访问
+func (s *Server) AdminAuth(ctx *gin.Context) {
+	username, password, ok := ctx.Request.BasicAuth()
+	if !ok {
+		ctx.Header("WWW-Authenticate", `Basic realm="kubejump"`)
+		utils.FailWithMessage(utils.AuthError, "", ctx)
+		ctx.Abort()
+		return
+	}
+	user, err := auth.HTTPBasicAuth(ctx, s.userService, username, password, ctx.GetHeader(otpCodeHeader), ctx.RemoteIP())
+	if err != nil {
+		utils.FailWithMessage(utils.AuthError, err.Error(), ctx)
+		ctx.Abort()
+		return
+	}
+	if !user.IsAdmin() {
+		klog.Warningf("HTTP user %s access %s %s forbidden", user, ctx.Request.Method, ctx.FullPath())
+		utils.FailWithMessage(utils.Forbidden, "", ctx)
+		ctx.Abort()
+		return
+	}
+	ctx.Set(ctxKeyCurrentUser, user)
+	ctx.Next()
+}
+
+// currentUser 返回通过 AdminAuth 认证的调用者
+func currentUser(ctx *gin.Context) *entity.User {
+	user, _ := ctx.MustGet(ctxKeyCurrentUser).(*entity.User)
+	return user
+}
@@ -0,0 +1,113 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+)
+
+func (s *Server) ListUserGroups(ctx *gin.Context) {
+	groups, err := s.permService.ListUserGroups(ctx)
+	if err != nil {
+		utils.FailWithMessage(utils.QueryUserGroupError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(groups, ctx)
+}
+
+func (s *Server) CreateUserGroup(ctx *gin.Context) {
+	req := new(entity.UserGroupReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	group, err := s.permService.CreateUserGroup(ctx, req)
+	if err != nil {
+		utils.FailWithMessage(utils.CreateUserGroupError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(group, ctx)
+}
+
+func (s *Server) UpdateUserGroup(ctx *gin.Context) {
+	groupID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	req := new(entity.UserGroupReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	group, err := s.permService.UpdateUserGroup(ctx, groupID, req)
+	if err != nil {
+		utils.FailWithMessage(utils.UpdateUserGroupError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(group, ctx)
+}
+
+func (s *Server) DeleteUserGroup(ctx *gin.Context) {
+	groupID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	if err := s.permService.DeleteUserGroup(ctx, groupID); err != nil {
+		utils.FailWithMessage(utils.UpdateUserGroupError, err.Error(), ctx)
+		return
+	}
+	utils.Ok(ctx)
+}
+
+func (s *Server) ListPermissions(ctx *gin.Context) {
+	perms, err := s.permService.ListPermissions(ctx)
+	if err != nil {
+		utils.FailWithMessage(utils.QueryPermissionError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(perms, ctx)
+}
+
+func (s *Server) CreatePermission(ctx *gin.Context) {
+	req := new(entity.AssetPermissionReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	perm, err := s.permService.CreatePermission(ctx, req)
+	if err != nil {
+		utils.FailWithMessage(utils.CreatePermissionError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(perm, ctx)
+}
+
+func (s *Server) UpdatePermission(ctx *gin.Context) {
+	permID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	req := new(entity.AssetPermissionReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	perm, err := s.permService.UpdatePermission(ctx, permID, req)
+	if err != nil {
+		utils.FailWithMessage(utils.UpdatePermissionError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(perm, ctx)
+}
+
+func (s *Server) DeletePermission(ctx *gin.Context) {
+	permID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	if err := s.permService.DeletePermission(ctx, permID); err != nil {
+		utils.FailWithMessage(utils.UpdatePermissionError, err.Error(), ctx)
+		return
+	}
+	utils.Ok(ctx)
+}
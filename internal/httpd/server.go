@@ -9,14 +9,19 @@ import (
 )
 
 type Server struct {
-	Srv        *http.Server
-	jmsService *service.JMService
+	Srv         *http.Server
+	jmsService  *service.JMService
+	userService *service.UserService
+	permService *service.PermissionService
 	//JmsService  *service.JMService
 }
 
-func NewServer(jmsService *service.JMService) *Server {
+func NewServer(jmsService *service.JMService, userService *service.UserService,
+	permService *service.PermissionService) *Server {
 	return &Server{
-		jmsService: jmsService,
+		jmsService:  jmsService,
+		userService: userService,
+		permService: permService,
 	}
 }
 
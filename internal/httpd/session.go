@@ -0,0 +1,57 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/session"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"sort"
+)
+
+// ListSessions 列出所有实例上的在线会话
+func (s *Server) ListSessions(ctx *gin.Context) {
+	data := session.ListAllSessions()
+	sort.Slice(data, func(i, j int) bool {
+		return data[i].DateStart.After(data[j].DateStart)
+	})
+	utils.OkWithData(data, ctx)
+}
+
+// CreateSessionTask 对在线会话下发终止、锁定、解锁任务
+func (s *Server) CreateSessionTask(ctx *gin.Context) {
+	req := new(entity.SessionTaskReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	task := &entity.TerminalTask{
+		ID:   utils.UUID(),
+		Name: req.Name,
+		Kwargs: entity.TaskKwargs{
+			TerminatedBy:  req.Operator,
+			CreatedByUser: req.Operator,
+		},
+	}
+	// 会话可能在其他实例上, 由 exchange 转发
+	if err := session.SendTask(ctx.Param("id"), task); err != nil {
+		utils.FailWithMessage(utils.SessionTaskError, err.Error(), ctx)
+		return
+	}
+	task.IsFinished = true
+	utils.OkWithData(task, ctx)
+}
+
+// ListSessionHistory 分页查询会话历史
+func (s *Server) ListSessionHistory(ctx *gin.Context) {
+	param := new(entity.SessionPaginationParam)
+	if err := ctx.ShouldBindQuery(param); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	resp, err := s.jmsService.ListSessionHistory(ctx, param)
+	if err != nil {
+		utils.FailWithMessage(utils.QuerySessionError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(resp, ctx)
+}
@@ -0,0 +1,106 @@
+package httpd
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gin-gonic/gin"
+	"strconv"
+)
+
+func (s *Server) ListUsers(ctx *gin.Context) {
+	param := new(entity.UserPaginationParam)
+	if err := ctx.ShouldBindQuery(param); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	resp, err := s.userService.ListUsers(ctx, param)
+	if err != nil {
+		utils.FailWithMessage(utils.QueryUserError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(resp, ctx)
+}
+
+func (s *Server) CreateUser(ctx *gin.Context) {
+	userReq := new(entity.UserCreateReq)
+	if err := utils.CheckParams(ctx, userReq); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	user, err := s.userService.CreateUser(ctx, userReq)
+	if err != nil {
+		utils.FailWithMessage(utils.CreateUserError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(user, ctx)
+}
+
+func (s *Server) UpdateUser(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	userReq := new(entity.UserUpdateReq)
+	if err := utils.CheckParams(ctx, userReq); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	user, err := s.userService.UpdateUser(ctx, userID, userReq)
+	if err != nil {
+		utils.FailWithMessage(utils.UpdateUserError, err.Error(), ctx)
+		return
+	}
+	utils.OkWithData(user, ctx)
+}
+
+func (s *Server) DisableUser(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	if err := s.userService.DisableUser(ctx, userID); err != nil {
+		utils.FailWithMessage(utils.UpdateUserError, err.Error(), ctx)
+		return
+	}
+	utils.Ok(ctx)
+}
+
+// UnlockUser 解除用户因连续登录失败导致的锁定
+func (s *Server) UnlockUser(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	if err := s.userService.UnlockUser(ctx, userID); err != nil {
+		utils.FailWithMessage(utils.UpdateUserError, err.Error(), ctx)
+		return
+	}
+	utils.Ok(ctx)
+}
+
+func (s *Server) ResetUserPassword(ctx *gin.Context) {
+	userID, ok := parseIDParam(ctx)
+	if !ok {
+		return
+	}
+	req := new(entity.UserResetPasswordReq)
+	if err := utils.CheckParams(ctx, req); err != nil {
+		utils.FailWithMessage(utils.ParamError, err.Error(), ctx)
+		return
+	}
+	if err := s.userService.ResetPassword(ctx, userID, req.Password); err != nil {
+		utils.FailWithMessage(utils.UpdateUserError, err.Error(), ctx)
+		return
+	}
+	utils.Ok(ctx)
+}
+
+// parseIDParam 解析路径中的 :id 参数, 失败时直接返回参数错误
+func parseIDParam(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil || id == 0 {
+		utils.FailWithMessage(utils.ParamError, "invalid id "+ctx.Param("id"), ctx)
+		return 0, false
+	}
+	return uint(id), true
+}
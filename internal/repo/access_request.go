@@ -0,0 +1,97 @@
+package repo
+
+import (
+	"context"
+	"errors"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"gorm.io/gorm"
+)
+
+type AccessRequestRepo struct {
+	data *data.Data
+}
+
+func (ar *AccessRequestRepo) CreateAccessRequest(_ context.Context, req *entity.AccessRequest) error {
+	return ar.data.DB.Session(&gorm.Session{}).Omit("User").Create(req).Error
+}
+
+func (ar *AccessRequestRepo) GetAccessRequest(_ context.Context, id uint) (*entity.AccessRequest, error) {
+	req := new(entity.AccessRequest)
+	db := ar.data.DB.Session(&gorm.Session{}).
+		Preload("User").
+		Where("id = ?", id).
+		Take(req)
+	return req, db.Error
+}
+
+func (ar *AccessRequestRepo) ReviewAccessRequest(_ context.Context, id uint, values map[string]interface{}) (bool, error) {
+	db := ar.data.DB.Session(&gorm.Session{}).
+		Model(&entity.AccessRequest{}).
+		Where("id = ? AND status = ?", id, entity.AccessRequestPending).
+		Updates(values)
+	return db.RowsAffected > 0, db.Error
+}
+
+func (ar *AccessRequestRepo) UpdateAccessRequest(_ context.Context, id uint, values map[string]interface{}) error {
+	return ar.data.DB.Session(&gorm.Session{}).
+		Model(&entity.AccessRequest{}).
+		Where("id = ?", id).
+		Updates(values).Error
+}
+
+// errAccessRequestNotPending 申请已被处理, 回滚已创建的授权规则
+var errAccessRequestNotPending = errors.New("access request is not pending")
+
+func (ar *AccessRequestRepo) ApproveAccessRequest(_ context.Context, id uint, values map[string]interface{},
+	perm *entity.AssetPermission, userIDs []uint) (bool, error) {
+	err := ar.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		perm.Users = usersFromIDs(userIDs)
+		if err := tx.Omit("Users.*", "UserGroups.*").Create(perm).Error; err != nil {
+			return err
+		}
+		values["permission_ref"] = perm.ID
+		db := tx.Model(&entity.AccessRequest{}).
+			Where("id = ? AND status = ?", id, entity.AccessRequestPending).
+			Updates(values)
+		if db.Error != nil {
+			return db.Error
+		}
+		if db.RowsAffected == 0 {
+			return errAccessRequestNotPending
+		}
+		return nil
+	})
+	if errors.Is(err, errAccessRequestNotPending) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (ar *AccessRequestRepo) ListAccessRequestsWithPager(_ context.Context, param *entity.AccessRequestPaginationParam) ([]*entity.AccessRequest, int, error) {
+	var count int64
+	result := make([]*entity.AccessRequest, 0)
+	filter := &entity.AccessRequest{
+		UserRef: param.UserID,
+		Status:  param.Status,
+		Kind:    param.Kind,
+	}
+	db := ar.data.DB.Session(&gorm.Session{}).
+		Model(&entity.AccessRequest{}).
+		Where(filter)
+
+	if err := db.Count(&count).Error; err != nil {
+		return result, 0, err
+	}
+
+	db = db.Preload("User").
+		Scopes(OrderBy("id desc"), Paginate(param.PageSize, param.PageNo)).
+		Find(&result)
+	return result, int(count), db.Error
+}
+
+func NewAccessRequestRepo() entity.AccessRequestRepo {
+	return &AccessRequestRepo{
+		data: data.DefaultData,
+	}
+}
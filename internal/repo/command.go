@@ -0,0 +1,56 @@
+package repo
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"gorm.io/gorm"
+)
+
+type CommandRepo struct {
+	data *data.Data
+}
+
+func (cr *CommandRepo) CreateCommand(_ context.Context, cmd *entity.Command) error {
+	return cr.data.DB.Session(&gorm.Session{}).Create(cmd).Error
+}
+
+func (cr *CommandRepo) ListCommandsWithPager(_ context.Context, param *entity.CommandPaginationParam) ([]*entity.Command, int, error) {
+	var count int64
+	result := make([]*entity.Command, 0)
+	filter := &entity.Command{
+		SessionID:   param.SessionID,
+		User:        param.User,
+		ClusterName: param.Cluster,
+		Namespace:   param.Namespace,
+		PodName:     param.PodName,
+
+		FilterAction: param.Action,
+	}
+	db := cr.data.DB.Session(&gorm.Session{}).
+		Model(&entity.Command{}).
+		Where(filter)
+	if param.Search != "" {
+		db = db.Where("input LIKE ?", "%"+escapeLike(param.Search)+"%")
+	}
+	if param.DateFrom != nil {
+		db = db.Where("timestamp >= ?", param.DateFrom)
+	}
+	if param.DateTo != nil {
+		db = db.Where("timestamp <= ?", param.DateTo)
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		return result, 0, err
+	}
+
+	db = db.Scopes(OrderBy("timestamp desc"), Paginate(param.PageSize, param.PageNo)).
+		Find(&result)
+	return result, int(count), db.Error
+}
+
+func NewCommandRepo() entity.CommandRepo {
+	return &CommandRepo{
+		data: data.DefaultData,
+	}
+}
@@ -0,0 +1,101 @@
+package repo
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"gorm.io/gorm"
+)
+
+type CommandFilterRepo struct {
+	data *data.Data
+}
+
+func (fr *CommandFilterRepo) CreateCommandFilter(_ context.Context, acl *entity.CommandFilterACL, userIDs, groupIDs []uint) error {
+	acl.Users = usersFromIDs(userIDs)
+	acl.UserGroups = userGroupsFromIDs(groupIDs)
+	return fr.data.DB.Session(&gorm.Session{}).Omit("Users.*", "UserGroups.*").Create(acl).Error
+}
+
+// UpdateCommandFilter userIDs、groupIDs 为 nil 时不修改对应的授权对象
+func (fr *CommandFilterRepo) UpdateCommandFilter(_ context.Context, aclID uint, values map[string]interface{}, userIDs, groupIDs []uint) error {
+	return fr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		acl := &entity.CommandFilterACL{BaseModel: entity.BaseModel{ID: aclID}}
+		if len(values) > 0 {
+			if err := tx.Model(acl).Updates(values).Error; err != nil {
+				return err
+			}
+		}
+		if userIDs != nil {
+			if err := tx.Model(acl).Association("Users").Replace(usersFromIDs(userIDs)); err != nil {
+				return err
+			}
+		}
+		if groupIDs != nil {
+			if err := tx.Model(acl).Association("UserGroups").Replace(userGroupsFromIDs(groupIDs)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (fr *CommandFilterRepo) DeleteCommandFilter(_ context.Context, aclID uint) error {
+	return fr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		acl := &entity.CommandFilterACL{BaseModel: entity.BaseModel{ID: aclID}}
+		if err := tx.Model(acl).Association("Users").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(acl).Association("UserGroups").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(acl).Error
+	})
+}
+
+func (fr *CommandFilterRepo) GetCommandFilter(_ context.Context, aclID uint) (*entity.CommandFilterACL, error) {
+	acl := new(entity.CommandFilterACL)
+	db := fr.data.DB.Session(&gorm.Session{}).
+		Preload("Users").
+		Preload("UserGroups").
+		Where("id = ?", aclID).
+		Take(acl)
+	return acl, db.Error
+}
+
+func (fr *CommandFilterRepo) ListCommandFilters(_ context.Context) ([]*entity.CommandFilterACL, error) {
+	acls := make([]*entity.CommandFilterACL, 0)
+	db := fr.data.DB.Session(&gorm.Session{}).
+		Preload("Users").
+		Preload("UserGroups").
+		Scopes(OrderBy("priority, id")).
+		Find(&acls)
+	return acls, db.Error
+}
+
+func (fr *CommandFilterRepo) ListUserCommandFilters(_ context.Context, userID uint) ([]*entity.CommandFilterACL, error) {
+	db := fr.data.DB.Session(&gorm.Session{})
+	byUser := db.Table("command_filter_acl_users").
+		Select("command_filter_acl_id").
+		Where("user_id = ?", userID)
+	byGroup := db.Table("command_filter_acl_user_groups").
+		Select("command_filter_acl_user_groups.command_filter_acl_id").
+		Joins("JOIN user_group_members ON user_group_members.user_group_id = command_filter_acl_user_groups.user_group_id").
+		Where("user_group_members.user_id = ?", userID)
+	assignedUsers := db.Table("command_filter_acl_users").Select("command_filter_acl_id")
+	assignedGroups := db.Table("command_filter_acl_user_groups").Select("command_filter_acl_id")
+
+	acls := make([]*entity.CommandFilterACL, 0)
+	err := db.Where("is_active = ?", true).
+		Where("id IN (?) OR id IN (?) OR (id NOT IN (?) AND id NOT IN (?))",
+			byUser, byGroup, assignedUsers, assignedGroups).
+		Scopes(OrderBy("priority, id")).
+		Find(&acls).Error
+	return acls, err
+}
+
+func NewCommandFilterRepo() entity.CommandFilterRepo {
+	return &CommandFilterRepo{
+		data: data.DefaultData,
+	}
+}
@@ -2,8 +2,11 @@ package repo
 
 import (
 	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
 	"github.com/daicheng123/kubejump/pkg/utils"
 	"gorm.io/gorm"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/klog/v2"
 	"strings"
 )
 
@@ -78,6 +81,93 @@ func SearchPodBy(search string) func(db *gorm.DB) *gorm.DB {
 	}
 }
 
+func SearchUserBy(search string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if len(search) == 0 {
+			return db
+		}
+		like := "%" + search + "%"
+		return db.Where("username like ? or name like ? or email like ?", like, like, like)
+	}
+}
+
+// PermittedPods 按授权规则过滤 pod, perms 为 nil 表示不做过滤, 为空表示无任何授权
+func PermittedPods(perms []*entity.AssetPermission) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if perms == nil {
+			return db
+		}
+
+		rules := make([]string, 0, len(perms))
+		args := make([]interface{}, 0)
+		for _, perm := range perms {
+			conds, condArgs, err := permissionConditions(perm)
+			if err != nil {
+				klog.Errorf("Skip invalid asset permission %s: %s", perm, err)
+				continue
+			}
+			rules = append(rules, "("+strings.Join(conds, " AND ")+")")
+			args = append(args, condArgs...)
+		}
+		if len(rules) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(strings.Join(rules, " OR "), args...)
+	}
+}
+
+func permissionConditions(perm *entity.AssetPermission) ([]string, []interface{}, error) {
+	requirements, err := perm.LabelRequirements()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	conds := []string{"1 = 1"}
+	args := make([]interface{}, 0)
+	if perm.ClusterName != "" {
+		conds = append(conds, "cluster_ref IN (SELECT uniq_key FROM clusters WHERE cluster_name = ? AND deleted_at IS NULL)")
+		args = append(args, perm.ClusterName)
+	}
+	if perm.Namespace != "" {
+		conds = append(conds, "namespace = ?")
+		args = append(args, perm.Namespace)
+	}
+	if perm.PodName != "" {
+		conds = append(conds, "pod_name = ?")
+		args = append(args, perm.PodName)
+	}
+	// labels 以 ",k1=v1,k2=v2," 格式存储
+	for _, r := range requirements {
+		key := escapeLike(r.Key())
+		switch r.Operator() {
+		case selection.Equals, selection.DoubleEquals, selection.In:
+			values := r.Values().List()
+			ors := make([]string, 0, len(values))
+			for _, v := range values {
+				ors = append(ors, "labels LIKE ?")
+				args = append(args, "%,"+key+"="+escapeLike(v)+",%")
+			}
+			conds = append(conds, "("+strings.Join(ors, " OR ")+")")
+		case selection.NotEquals, selection.NotIn:
+			for _, v := range r.Values().List() {
+				conds = append(conds, "(labels IS NULL OR labels NOT LIKE ?)")
+				args = append(args, "%,"+key+"="+escapeLike(v)+",%")
+			}
+		case selection.Exists:
+			conds = append(conds, "labels LIKE ?")
+			args = append(args, "%,"+key+"=%")
+		case selection.DoesNotExist:
+			conds = append(conds, "(labels IS NULL OR labels NOT LIKE ?)")
+			args = append(args, "%,"+key+"=%")
+		}
+	}
+	return conds, args, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
 func PaginatePods(pageSize int, offset int) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		//if offset <= 0 {
@@ -0,0 +1,55 @@
+package repo
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"gorm.io/gorm"
+)
+
+type FTPLogRepo struct {
+	data *data.Data
+}
+
+func (fr *FTPLogRepo) CreateFTPLog(_ context.Context, log *entity.FTPLog) error {
+	return fr.data.DB.Session(&gorm.Session{}).Create(log).Error
+}
+
+func (fr *FTPLogRepo) ListFTPLogsWithPager(_ context.Context, param *entity.FTPLogPaginationParam) ([]*entity.FTPLog, int, error) {
+	var count int64
+	result := make([]*entity.FTPLog, 0)
+	filter := &entity.FTPLog{
+		SessionID:   param.SessionID,
+		User:        param.User,
+		ClusterName: param.Cluster,
+		Namespace:   param.Namespace,
+		PodName:     param.PodName,
+		Operate:     param.Operate,
+	}
+	db := fr.data.DB.Session(&gorm.Session{}).
+		Model(&entity.FTPLog{}).
+		Where(filter)
+	if param.Search != "" {
+		db = db.Where("path LIKE ?", "%"+escapeLike(param.Search)+"%")
+	}
+	if param.DateFrom != nil {
+		db = db.Where("timestamp >= ?", param.DateFrom)
+	}
+	if param.DateTo != nil {
+		db = db.Where("timestamp <= ?", param.DateTo)
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		return result, 0, err
+	}
+
+	db = db.Scopes(OrderBy("timestamp desc"), Paginate(param.PageSize, param.PageNo)).
+		Find(&result)
+	return result, int(count), db.Error
+}
+
+func NewFTPLogRepo() entity.FTPLogRepo {
+	return &FTPLogRepo{
+		data: data.DefaultData,
+	}
+}
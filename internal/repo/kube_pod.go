@@ -25,11 +25,64 @@ func (pr *PodRepo) CreateOrUpdatePod(_ context.Context, pod *entity.Pod) error {
 		{Name: "namespace"},
 		{Name: "cluster_ref"},
 	}
-	tx := pr.data.DB.Session(&gorm.Session{}).Clauses(clause.OnConflict{
+	return pr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.OnConflict{
+			UpdateAll: true,
+			Columns:   conflictKeys,
+		}).Create(pod).Error; err != nil {
+			return err
+		}
+		return syncPodContainers(tx, pod)
+	})
+}
+
+// syncPodContainers 以 pod 事件中的容器列表为准, 删除已不存在的容器
+func syncPodContainers(tx *gorm.DB, pod *entity.Pod) error {
+	names := make([]string, 0, len(pod.Containers))
+	for _, container := range pod.Containers {
+		container.PodName = pod.PodName
+		container.Namespace = pod.Namespace
+		container.ClusterRef = pod.ClusterRef
+		names = append(names, container.ContainerName)
+	}
+	stale := tx.Unscoped().Where(podContainerFilter(pod))
+	if len(names) > 0 {
+		stale = stale.Where("container_name NOT IN ?", names)
+	}
+	if err := stale.Delete(&entity.Container{}).Error; err != nil {
+		return err
+	}
+	if len(pod.Containers) == 0 {
+		return nil
+	}
+	conflictKeys := []clause.Column{
+		{Name: "container_name"},
+		{Name: "pod_name"},
+		{Name: "namespace"},
+		{Name: "cluster_ref"},
+	}
+	return tx.Clauses(clause.OnConflict{
 		UpdateAll: true,
 		Columns:   conflictKeys,
-	})
-	return tx.Create(pod).Error
+	}).Create(pod.Containers).Error
+}
+
+func podContainerFilter(pod *entity.Pod) *entity.Container {
+	return &entity.Container{
+		PodName:    pod.PodName,
+		Namespace:  pod.Namespace,
+		ClusterRef: pod.ClusterRef,
+	}
+}
+
+func (pr *PodRepo) ListPodContainers(_ context.Context, pod *entity.Pod) ([]*entity.Container, error) {
+	containers := make([]*entity.Container, 0)
+	db := pr.data.DB.Session(&gorm.Session{}).
+		Model(&entity.Container{}).
+		Where(podContainerFilter(pod)).
+		Scopes(OrderBy("id asc")).
+		Find(&containers)
+	return containers, db.Error
 }
 func validConvertNum(str string) (int64, error) {
 	return strconv.ParseInt(str, 10, 64)
@@ -53,7 +106,7 @@ func (pr *PodRepo) ListPodsWithPreLoadCluster(_ context.Context, filter *entity.
 	return podList, db.Error
 }
 
-func (pr *PodRepo) PreloadPodsWithPager(_ context.Context, filter *entity.Pod, reqParam *entity.PaginationParam) ([]*entity.Pod, int, error) {
+func (pr *PodRepo) PreloadPodsWithPager(_ context.Context, filter *entity.Pod, reqParam *entity.PaginationParam, perms []*entity.AssetPermission) ([]*entity.Pod, int, error) {
 	var count int64
 	if filter == nil {
 		filter = &entity.Pod{}
@@ -66,6 +119,7 @@ func (pr *PodRepo) PreloadPodsWithPager(_ context.Context, filter *entity.Pod, r
 		Scopes(
 			OrderBy(reqParam.SortBy),
 			SearchPodBy(reqParam.Search),
+			PermittedPods(perms),
 		)
 
 	db.Count(&count)
@@ -92,8 +146,12 @@ func (pr *PodRepo) DeletePodByNameAndNamespace(_ context.Context, name, ns, clus
 
 	pr.lock.Lock()
 	defer pr.lock.Unlock()
-	db := pr.data.DB.Session(&gorm.Session{}).Delete(filter)
-	return db.Error
+	return pr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where(filter).Delete(&entity.Pod{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Where(podContainerFilter(filter)).Delete(&entity.Container{}).Error
+	})
 }
 
 func NewPodRepo() entity.PodRepo {
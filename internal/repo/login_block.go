@@ -0,0 +1,83 @@
+package repo
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"sync"
+	"time"
+)
+
+const (
+	loginFailedKeyPrefix  = "login_failed:"
+	loginBlockedKeyPrefix = "login_blocked:"
+	loginSourceKeyPrefix  = "login_failed_sources:"
+)
+
+type LoginBlockRepo struct {
+	data *data.Data
+	// 保护失败来源列表的读写
+	sourceMu sync.Mutex
+}
+
+func (lr *LoginBlockRepo) IncrFailedCount(_ context.Context, key string, window time.Duration) (int, error) {
+	cacheKey := loginFailedKeyPrefix + key
+	// 仅在第一次失败时设置统计窗口
+	_ = lr.data.LocalCache.Add(cacheKey, 0, window)
+	return lr.data.LocalCache.IncrementInt(cacheKey, 1)
+}
+
+func (lr *LoginBlockRepo) ClearFailedCount(_ context.Context, key string) {
+	lr.data.LocalCache.Delete(loginFailedKeyPrefix + key)
+}
+
+func (lr *LoginBlockRepo) Block(_ context.Context, key string, duration time.Duration) {
+	lr.data.LocalCache.Set(loginBlockedKeyPrefix+key, time.Now().Add(duration), duration)
+}
+
+func (lr *LoginBlockRepo) IsBlocked(_ context.Context, key string) bool {
+	_, ok := lr.data.LocalCache.Get(loginBlockedKeyPrefix + key)
+	return ok
+}
+
+func (lr *LoginBlockRepo) Unblock(_ context.Context, key string) {
+	lr.data.LocalCache.Delete(loginBlockedKeyPrefix + key)
+	lr.data.LocalCache.Delete(loginFailedKeyPrefix + key)
+}
+
+func (lr *LoginBlockRepo) AddFailedSource(_ context.Context, key, source string, expire time.Duration) {
+	lr.sourceMu.Lock()
+	defer lr.sourceMu.Unlock()
+	cacheKey := loginSourceKeyPrefix + key
+	var sources []string
+	if value, ok := lr.data.LocalCache.Get(cacheKey); ok {
+		sources, _ = value.([]string)
+	}
+	for _, item := range sources {
+		if item == source {
+			lr.data.LocalCache.Set(cacheKey, sources, expire)
+			return
+		}
+	}
+	sources = append(append([]string(nil), sources...), source)
+	lr.data.LocalCache.Set(cacheKey, sources, expire)
+}
+
+func (lr *LoginBlockRepo) PopFailedSources(_ context.Context, key string) []string {
+	lr.sourceMu.Lock()
+	defer lr.sourceMu.Unlock()
+	cacheKey := loginSourceKeyPrefix + key
+	value, ok := lr.data.LocalCache.Get(cacheKey)
+	if !ok {
+		return nil
+	}
+	lr.data.LocalCache.Delete(cacheKey)
+	sources, _ := value.([]string)
+	return sources
+}
+
+func NewLoginBlockRepo() entity.LoginBlockRepo {
+	return &LoginBlockRepo{
+		data: data.DefaultData,
+	}
+}
@@ -0,0 +1,191 @@
+package repo
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"gorm.io/gorm"
+	"time"
+)
+
+type AssetPermissionRepo struct {
+	data *data.Data
+}
+
+func (pr *AssetPermissionRepo) CreatePermission(_ context.Context, perm *entity.AssetPermission, userIDs, groupIDs []uint) error {
+	perm.Users = usersFromIDs(userIDs)
+	perm.UserGroups = userGroupsFromIDs(groupIDs)
+	return pr.data.DB.Session(&gorm.Session{}).Omit("Users.*", "UserGroups.*").Create(perm).Error
+}
+
+// UpdatePermission userIDs、groupIDs 为 nil 时不修改对应的授权对象
+func (pr *AssetPermissionRepo) UpdatePermission(_ context.Context, permID uint, values map[string]interface{}, userIDs, groupIDs []uint) error {
+	return pr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		perm := &entity.AssetPermission{BaseModel: entity.BaseModel{ID: permID}}
+		if len(values) > 0 {
+			if err := tx.Model(perm).Updates(values).Error; err != nil {
+				return err
+			}
+		}
+		if userIDs != nil {
+			if err := tx.Model(perm).Association("Users").Replace(usersFromIDs(userIDs)); err != nil {
+				return err
+			}
+		}
+		if groupIDs != nil {
+			if err := tx.Model(perm).Association("UserGroups").Replace(userGroupsFromIDs(groupIDs)); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (pr *AssetPermissionRepo) DeletePermission(_ context.Context, permID uint) error {
+	return pr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		perm := &entity.AssetPermission{BaseModel: entity.BaseModel{ID: permID}}
+		if err := tx.Model(perm).Association("Users").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Model(perm).Association("UserGroups").Clear(); err != nil {
+			return err
+		}
+		return tx.Delete(perm).Error
+	})
+}
+
+func (pr *AssetPermissionRepo) GetPermission(_ context.Context, permID uint) (*entity.AssetPermission, error) {
+	perm := new(entity.AssetPermission)
+	db := pr.data.DB.Session(&gorm.Session{}).
+		Preload("Users").
+		Preload("UserGroups").
+		Where("id = ?", permID).
+		Take(perm)
+	return perm, db.Error
+}
+
+func (pr *AssetPermissionRepo) ListPermissions(_ context.Context) ([]*entity.AssetPermission, error) {
+	perms := make([]*entity.AssetPermission, 0)
+	db := pr.data.DB.Session(&gorm.Session{}).
+		Preload("Users").
+		Preload("UserGroups").
+		Scopes(OrderBy("id")).
+		Find(&perms)
+	return perms, db.Error
+}
+
+// ListUserPermissions 查询直接授予用户或通过用户组授予的有效期内的授权规则
+func (pr *AssetPermissionRepo) ListUserPermissions(_ context.Context, userID uint) ([]*entity.AssetPermission, error) {
+	now := time.Now()
+	db := pr.data.DB.Session(&gorm.Session{})
+	byUser := db.Table("asset_permission_users").
+		Select("asset_permission_id").
+		Where("user_id = ?", userID)
+	byGroup := db.Table("asset_permission_user_groups").
+		Select("asset_permission_user_groups.asset_permission_id").
+		Joins("JOIN user_group_members ON user_group_members.user_group_id = asset_permission_user_groups.user_group_id").
+		Where("user_group_members.user_id = ?", userID)
+
+	perms := make([]*entity.AssetPermission, 0)
+	err := db.Where("is_active = ?", true).
+		Where("date_start IS NULL OR date_start <= ?", now).
+		Where("date_expired IS NULL OR date_expired > ?", now).
+		Where("id IN (?) OR id IN (?)", byUser, byGroup).
+		Find(&perms).Error
+	return perms, err
+}
+
+func NewAssetPermissionRepo() entity.AssetPermissionRepo {
+	return &AssetPermissionRepo{
+		data: data.DefaultData,
+	}
+}
+
+type UserGroupRepo struct {
+	data *data.Data
+}
+
+func (gr *UserGroupRepo) CreateUserGroup(_ context.Context, group *entity.UserGroup, userIDs []uint) error {
+	group.Users = usersFromIDs(userIDs)
+	return gr.data.DB.Session(&gorm.Session{}).Omit("Users.*").Create(group).Error
+}
+
+// UpdateUserGroup userIDs 为 nil 时不修改组成员
+func (gr *UserGroupRepo) UpdateUserGroup(_ context.Context, groupID uint, values map[string]interface{}, userIDs []uint) error {
+	return gr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		group := &entity.UserGroup{BaseModel: entity.BaseModel{ID: groupID}}
+		if len(values) > 0 {
+			if err := tx.Model(group).Updates(values).Error; err != nil {
+				return err
+			}
+		}
+		if userIDs != nil {
+			return tx.Model(group).Association("Users").Replace(usersFromIDs(userIDs))
+		}
+		return nil
+	})
+}
+
+func (gr *UserGroupRepo) DeleteUserGroup(_ context.Context, groupID uint) error {
+	return gr.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		group := &entity.UserGroup{BaseModel: entity.BaseModel{ID: groupID}}
+		if err := tx.Model(group).Association("Users").Clear(); err != nil {
+			return err
+		}
+		if err := tx.Table("asset_permission_user_groups").
+			Where("user_group_id = ?", groupID).
+			Delete(nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(group).Error
+	})
+}
+
+func (gr *UserGroupRepo) GetUserGroup(_ context.Context, groupID uint) (*entity.UserGroup, error) {
+	group := new(entity.UserGroup)
+	db := gr.data.DB.Session(&gorm.Session{}).
+		Preload("Users").
+		Where("id = ?", groupID).
+		Take(group)
+	return group, db.Error
+}
+
+func (gr *UserGroupRepo) ListUserGroups(_ context.Context) ([]*entity.UserGroup, error) {
+	groups := make([]*entity.UserGroup, 0)
+	db := gr.data.DB.Session(&gorm.Session{}).
+		Preload("Users").
+		Scopes(OrderBy("id")).
+		Find(&groups)
+	return groups, db.Error
+}
+
+func (gr *UserGroupRepo) CountUserGroupsByIDs(_ context.Context, ids []uint) (int, error) {
+	var count int64
+	err := gr.data.DB.Session(&gorm.Session{}).
+		Model(&entity.UserGroup{}).
+		Where("id IN ?", ids).
+		Count(&count).Error
+	return int(count), err
+}
+
+func NewUserGroupRepo() entity.UserGroupRepo {
+	return &UserGroupRepo{
+		data: data.DefaultData,
+	}
+}
+
+func usersFromIDs(ids []uint) []*entity.User {
+	users := make([]*entity.User, 0, len(ids))
+	for _, id := range ids {
+		users = append(users, &entity.User{BaseModel: entity.BaseModel{ID: id}})
+	}
+	return users
+}
+
+func userGroupsFromIDs(ids []uint) []*entity.UserGroup {
+	groups := make([]*entity.UserGroup, 0, len(ids))
+	for _, id := range ids {
+		groups = append(groups, &entity.UserGroup{BaseModel: entity.BaseModel{ID: id}})
+	}
+	return groups
+}
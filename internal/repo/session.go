@@ -0,0 +1,64 @@
+package repo
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/base/data"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"gorm.io/gorm"
+)
+
+type SessionRepo struct {
+	data *data.Data
+}
+
+func (sr *SessionRepo) CreateSession(_ context.Context, sess *entity.Session) error {
+	return sr.data.DB.Session(&gorm.Session{}).Create(sess).Error
+}
+
+func (sr *SessionRepo) FinishSession(_ context.Context, sess *entity.Session) error {
+	return sr.data.DB.Session(&gorm.Session{}).
+		Model(&entity.Session{}).
+		Where("id = ?", sess.ID).
+		Updates(map[string]interface{}{
+			"date_end":      sess.DateEnd,
+			"exit_reason":   sess.ExitReason,
+			"terminated_by": sess.TerminatedBy,
+		}).Error
+}
+
+func (sr *SessionRepo) ListSessionsWithPager(_ context.Context, param *entity.SessionPaginationParam) ([]*entity.Session, int, error) {
+	var count int64
+	result := make([]*entity.Session, 0)
+	filter := &entity.Session{
+		User:         param.User,
+		ClusterName:  param.Cluster,
+		Namespace:    param.Namespace,
+		PodName:      param.PodName,
+		RemoteAddr:   param.RemoteAddr,
+		ExitReason:   param.ExitReason,
+		TerminatedBy: param.TerminatedBy,
+	}
+	db := sr.data.DB.Session(&gorm.Session{}).
+		Model(&entity.Session{}).
+		Where(filter)
+	if param.DateFrom != nil {
+		db = db.Where("date_start >= ?", param.DateFrom)
+	}
+	if param.DateTo != nil {
+		db = db.Where("date_start <= ?", param.DateTo)
+	}
+
+	if err := db.Count(&count).Error; err != nil {
+		return result, 0, err
+	}
+
+	db = db.Scopes(OrderBy("date_start desc"), Paginate(param.PageSize, param.PageNo)).
+		Find(&result)
+	return result, int(count), db.Error
+}
+
+func NewSessionRepo() entity.SessionRepo {
+	return &SessionRepo{
+		data: data.DefaultData,
+	}
+}
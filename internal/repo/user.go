@@ -17,7 +17,7 @@ func (ur *UserRepo) GetInfoByID(_ context.Context, filter *entity.User) (*entity
 		return nil, errors.New("filter is Nil")
 	}
 
-	var userInfo *entity.User
+	var userInfo = new(entity.User)
 	db := ur.data.DB.Session(&gorm.Session{}).Where(filter).Take(userInfo)
 	return userInfo, db.Error
 }
@@ -29,6 +29,79 @@ func (ur *UserRepo) GetInfoByName(_ context.Context, username string, user *enti
 	return ur.data.DB.Session(&gorm.Session{}).Where("username=?", username).Take(user).Error
 }
 
+func (ur *UserRepo) ListPublicKeys(_ context.Context, userID uint) ([]*entity.UserPublicKey, error) {
+	var keys = make([]*entity.UserPublicKey, 0)
+	db := ur.data.DB.Session(&gorm.Session{}).Where("user_ref = ?", userID).Find(&keys)
+	return keys, db.Error
+}
+
+func (ur *UserRepo) UpdatePassword(_ context.Context, userID uint, hashedPassword string) error {
+	return ur.data.DB.Session(&gorm.Session{}).
+		Model(&entity.User{}).
+		Where("id = ?", userID).
+		Update("password", hashedPassword).Error
+}
+
+func (ur *UserRepo) UpdateOTPSecret(_ context.Context, userID uint, secret string) error {
+	return ur.data.DB.Session(&gorm.Session{}).
+		Model(&entity.User{}).
+		Where("id = ?", userID).
+		Update("otp_secret_key", secret).Error
+}
+
+func (ur *UserRepo) CreateUser(_ context.Context, user *entity.User) error {
+	return ur.data.DB.Session(&gorm.Session{}).Create(user).Error
+}
+
+func (ur *UserRepo) UpdateUser(_ context.Context, userID uint, values map[string]interface{}) error {
+	if len(values) == 0 {
+		return nil
+	}
+	return ur.data.DB.Session(&gorm.Session{}).
+		Model(&entity.User{}).
+		Where("id = ?", userID).
+		Updates(values).Error
+}
+
+func (ur *UserRepo) ReplacePublicKeys(_ context.Context, userID uint, keys []*entity.UserPublicKey) error {
+	return ur.data.DB.Session(&gorm.Session{}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("user_ref = ?", userID).Delete(&entity.UserPublicKey{}).Error; err != nil {
+			return err
+		}
+		if len(keys) == 0 {
+			return nil
+		}
+		for i := range keys {
+			keys[i].UserRef = userID
+		}
+		return tx.Create(keys).Error
+	})
+}
+
+func (ur *UserRepo) ListUsersWithPager(_ context.Context, param *entity.UserPaginationParam) ([]*entity.User, int, error) {
+	var count int64
+	result := make([]*entity.User, 0)
+	db := ur.data.DB.Session(&gorm.Session{}).
+		Model(&entity.User{}).
+		Scopes(SearchUserBy(param.Search))
+
+	if err := db.Count(&count).Error; err != nil {
+		return result, 0, err
+	}
+
+	db = db.Scopes(OrderBy("id"), Paginate(param.PageSize, param.PageNo)).Find(&result)
+	return result, int(count), db.Error
+}
+
+func (ur *UserRepo) CountUsersByIDs(_ context.Context, ids []uint) (int, error) {
+	var count int64
+	err := ur.data.DB.Session(&gorm.Session{}).
+		Model(&entity.User{}).
+		Where("id IN ?", ids).
+		Count(&count).Error
+	return int(count), err
+}
+
 func NewUserRepo() entity.UserRepo {
 	return &UserRepo{
 		data: data.DefaultData,
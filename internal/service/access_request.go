@@ -0,0 +1,265 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAccessRequestPageSize = 20
+	defaultAccessRequestDuration = 60
+	defaultAccessRequestTimeout  = 10
+
+	accessRequestPollInterval = 3 * time.Second
+)
+
+var ErrAccessRequestTimeout = errors.New("not reviewed")
+
+// ParseAccessTarget 解析 cluster/namespace[/pod] 格式的申请目标
+func ParseAccessTarget(target string) (cluster, namespace, pod string, err error) {
+	parts := strings.Split(strings.Trim(strings.TrimSpace(target), "/"), "/")
+	switch len(parts) {
+	case 2:
+		cluster, namespace = parts[0], parts[1]
+	case 3:
+		cluster, namespace, pod = parts[0], parts[1], parts[2]
+	default:
+		return "", "", "", fmt.Errorf("invalid target %q, expect cluster/namespace[/pod]", target)
+	}
+	if cluster == "" || namespace == "" {
+		return "", "", "", fmt.Errorf("invalid target %q, expect cluster/namespace[/pod]", target)
+	}
+	return
+}
+
+// checkAccessDuration 申请及审批调整的授权时长都不能超过配置的上限
+func checkAccessDuration(duration int) error {
+	if maxDuration := config.GetConf().AccessRequestMaxDuration; maxDuration > 0 && duration > maxDuration {
+		return fmt.Errorf("duration must not exceed %d minutes", maxDuration)
+	}
+	return nil
+}
+
+func (jms *JMService) CreateAccessRequest(ctx context.Context, user *entity.User, target, reason string, duration int) (*entity.AccessRequest, error) {
+	cluster, namespace, pod, err := ParseAccessTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	if duration <= 0 {
+		duration = defaultAccessRequestDuration
+	}
+	if err = checkAccessDuration(duration); err != nil {
+		return nil, err
+	}
+
+	pods, err := jms.podRepo.ListPodsWithPreLoadCluster(ctx, &entity.Pod{Namespace: namespace, PodName: pod}, "")
+	if err != nil {
+		return nil, err
+	}
+	var found bool
+	for i := range pods {
+		if pods[i].Cluster != nil && pods[i].Cluster.ClusterName == cluster {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("not found matched asset %s", target)
+	}
+
+	req := &entity.AccessRequest{
+		Kind:        entity.AccessRequestKindAsset,
+		UserRef:     user.ID,
+		ClusterName: cluster,
+		Namespace:   namespace,
+		PodName:     pod,
+		Reason:      reason,
+		Duration:    duration,
+		Status:      entity.AccessRequestPending,
+	}
+	if err = jms.accessRepo.CreateAccessRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	klog.Infof("User %s request access to %s for %d minutes: %s", user, req.Target(), duration, reason)
+	return req, nil
+}
+
+// CreateCommandReviewRequest 命中需要审批的命令过滤规则时发起命令审批
+func (jms *JMService) CreateCommandReviewRequest(ctx context.Context, user *entity.User, asset *entity.Asset,
+	sessionID, command, reason string) (*entity.AccessRequest, error) {
+	req := &entity.AccessRequest{
+		Kind:        entity.AccessRequestKindCommand,
+		UserRef:     user.ID,
+		ClusterName: asset.ClusterName,
+		Namespace:   asset.Namespace,
+		PodName:     asset.PodName,
+		Reason:      reason,
+		Status:      entity.AccessRequestPending,
+		SessionID:   sessionID,
+		Command:     command,
+	}
+	if err := jms.accessRepo.CreateAccessRequest(ctx, req); err != nil {
+		return nil, err
+	}
+	klog.Infof("User %s request to execute command %q on %s", user, command, req.Target())
+	return req, nil
+}
+
+// WaitAccessRequest 轮询申请状态直至审批完成, ctx 结束或等待超时后撤销申请
+func (jms *JMService) WaitAccessRequest(ctx context.Context, id uint) (*entity.AccessRequest, error) {
+	timeout := time.Duration(config.GetConf().AccessRequestTimeout) * time.Minute
+	if timeout <= 0 {
+		timeout = defaultAccessRequestTimeout * time.Minute
+	}
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	ticker := time.NewTicker(accessRequestPollInterval)
+	defer ticker.Stop()
+
+	cancelRequest := func() {
+		if err := jms.CancelAccessRequest(context.Background(), id); err != nil {
+			klog.Errorf("Cancel access request %d failed: %s", id, err)
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			cancelRequest()
+			return nil, ctx.Err()
+		case <-deadline.C:
+			cancelRequest()
+			return nil, fmt.Errorf("%w in %s", ErrAccessRequestTimeout, timeout)
+		case <-ticker.C:
+			latest, err := jms.accessRepo.GetAccessRequest(ctx, id)
+			if err != nil {
+				klog.Errorf("Get access request %d failed: %s", id, err)
+				continue
+			}
+			if latest.Status != entity.AccessRequestPending {
+				return latest, nil
+			}
+		}
+	}
+}
+
+func (jms *JMService) GetAccessRequest(ctx context.Context, id uint) (*entity.AccessRequest, error) {
+	return jms.accessRepo.GetAccessRequest(ctx, id)
+}
+
+func (jms *JMService) ListAccessRequests(ctx context.Context, param *entity.AccessRequestPaginationParam) (*entity.AccessRequestListResponse, error) {
+	if param.PageSize <= 0 {
+		param.PageSize = defaultAccessRequestPageSize
+	}
+	reqs, count, err := jms.accessRepo.ListAccessRequestsWithPager(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.AccessRequestListResponse{Total: count, Data: reqs}, nil
+}
+
+func checkReviewer(req *entity.AccessRequest, review *entity.AccessRequestReviewReq) error {
+	if review.ReviewerID == req.UserRef {
+		return fmt.Errorf("can not review your own access request %d", req.ID)
+	}
+	return nil
+}
+
+func reviewValues(status string, review *entity.AccessRequestReviewReq) map[string]interface{} {
+	now := time.Now()
+	return map[string]interface{}{
+		"status":         status,
+		"reviewer":       review.Reviewer,
+		"review_comment": review.Comment,
+		"date_reviewed":  &now,
+	}
+}
+
+func (jms *JMService) reviewAccessRequest(ctx context.Context, req *entity.AccessRequest, status string, review *entity.AccessRequestReviewReq) error {
+	if err := checkReviewer(req, review); err != nil {
+		return err
+	}
+	id := req.ID
+	ok, err := jms.accessRepo.ReviewAccessRequest(ctx, id, reviewValues(status, review))
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return fmt.Errorf("access request %d is not pending", id)
+	}
+	return nil
+}
+
+// ApproveAccessRequest 审批通过后为申请人创建一条到期自动失效的授权规则,
+// 授权规则与审批状态在同一事务中写入, 申请人看到通过时授权已经生效
+func (jms *JMService) ApproveAccessRequest(ctx context.Context, id uint, review *entity.AccessRequestReviewReq) (*entity.AccessRequest, error) {
+	req, err := jms.accessRepo.GetAccessRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if req.Kind == entity.AccessRequestKindCommand {
+		if err = jms.reviewAccessRequest(ctx, req, entity.AccessRequestApproved, review); err != nil {
+			return nil, err
+		}
+		klog.Infof("Command review %d in session %s approved by %s", id, req.SessionID, review.Reviewer)
+		return jms.accessRepo.GetAccessRequest(ctx, id)
+	}
+	if err = checkReviewer(req, review); err != nil {
+		return nil, err
+	}
+	duration := req.Duration
+	if review.Duration > 0 {
+		if err = checkAccessDuration(review.Duration); err != nil {
+			return nil, err
+		}
+		duration = review.Duration
+	}
+	dateStart := time.Now()
+	dateExpired := dateStart.Add(time.Duration(duration) * time.Minute)
+	perm := &entity.AssetPermission{
+		Name:        fmt.Sprintf("access-request-%d", req.ID),
+		ClusterName: req.ClusterName,
+		Namespace:   req.Namespace,
+		PodName:     req.PodName,
+		IsActive:    true,
+		DateStart:   &dateStart,
+		DateExpired: &dateExpired,
+		Comment:     req.Reason,
+	}
+	values := reviewValues(entity.AccessRequestApproved, review)
+	values["duration"] = duration
+	ok, err := jms.accessRepo.ApproveAccessRequest(ctx, id, values, perm, []uint{req.UserRef})
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("access request %d is not pending", id)
+	}
+	klog.Infof("Access request %d for %s approved by %s", id, req.Target(), review.Reviewer)
+	return jms.accessRepo.GetAccessRequest(ctx, id)
+}
+
+func (jms *JMService) RejectAccessRequest(ctx context.Context, id uint, review *entity.AccessRequestReviewReq) (*entity.AccessRequest, error) {
+	req, err := jms.accessRepo.GetAccessRequest(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if err = jms.reviewAccessRequest(ctx, req, entity.AccessRequestRejected, review); err != nil {
+		return nil, err
+	}
+	klog.Infof("Access request %d rejected by %s", id, review.Reviewer)
+	return jms.accessRepo.GetAccessRequest(ctx, id)
+}
+
+// CancelAccessRequest 申请人断开连接或等待超时时撤销申请
+func (jms *JMService) CancelAccessRequest(ctx context.Context, id uint) error {
+	_, err := jms.accessRepo.ReviewAccessRequest(ctx, id, map[string]interface{}{
+		"status": entity.AccessRequestCanceled,
+	})
+	return err
+}
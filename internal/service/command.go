@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"k8s.io/klog/v2"
+)
+
+const defaultCommandPageSize = 20
+
+func (jms *JMService) RecordCommand(ctx context.Context, cmd *entity.Command) {
+	if err := jms.commandRepo.CreateCommand(ctx, cmd); err != nil {
+		klog.Errorf("Session[%s] record command %q failed: %s", cmd.SessionID, cmd.Input, err)
+	}
+}
+
+func (jms *JMService) ListCommands(ctx context.Context, param *entity.CommandPaginationParam) (*entity.CommandListResponse, error) {
+	if param.PageSize <= 0 {
+		param.PageSize = defaultCommandPageSize
+	}
+	cmds, count, err := jms.commandRepo.ListCommandsWithPager(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.CommandListResponse{Total: count, Data: cmds}, nil
+}
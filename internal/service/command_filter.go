@@ -0,0 +1,105 @@
+package service
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"k8s.io/klog/v2"
+)
+
+func (ps *PermissionService) ListCommandFilters(ctx context.Context) ([]*entity.CommandFilterACL, error) {
+	return ps.filterRepo.ListCommandFilters(ctx)
+}
+
+func (ps *PermissionService) buildCommandFilter(ctx context.Context, req *entity.CommandFilterReq) (*entity.CommandFilterACL, error) {
+	if err := ps.checkUsers(ctx, req.Users); err != nil {
+		return nil, err
+	}
+	if err := ps.checkUserGroups(ctx, req.UserGroups); err != nil {
+		return nil, err
+	}
+	acl := &entity.CommandFilterACL{
+		Name:        req.Name,
+		Priority:    req.Priority,
+		ClusterName: req.ClusterName,
+		Namespace:   req.Namespace,
+		Type:        req.Type,
+		Content:     req.Content,
+		IgnoreCase:  req.IgnoreCase,
+		Action:      req.Action,
+		IsActive:    true,
+		Comment:     req.Comment,
+	}
+	if acl.Priority == 0 {
+		acl.Priority = entity.DefaultCommandFilterPriority
+	}
+	if req.IsActive != nil {
+		acl.IsActive = *req.IsActive
+	}
+	if err := acl.Compile(); err != nil {
+		return nil, err
+	}
+	return acl, nil
+}
+
+func (ps *PermissionService) CreateCommandFilter(ctx context.Context, req *entity.CommandFilterReq) (*entity.CommandFilterACL, error) {
+	acl, err := ps.buildCommandFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = ps.filterRepo.CreateCommandFilter(ctx, acl, req.Users, req.UserGroups); err != nil {
+		return nil, err
+	}
+	return ps.filterRepo.GetCommandFilter(ctx, acl.ID)
+}
+
+// UpdateCommandFilter 请求中 users、user_groups 为 null 时保留原有授权对象
+func (ps *PermissionService) UpdateCommandFilter(ctx context.Context, aclID uint, req *entity.CommandFilterReq) (*entity.CommandFilterACL, error) {
+	if _, err := ps.filterRepo.GetCommandFilter(ctx, aclID); err != nil {
+		return nil, err
+	}
+	acl, err := ps.buildCommandFilter(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{
+		"name":         acl.Name,
+		"priority":     acl.Priority,
+		"cluster_name": acl.ClusterName,
+		"namespace":    acl.Namespace,
+		"type":         acl.Type,
+		"content":      acl.Content,
+		"ignore_case":  acl.IgnoreCase,
+		"action":       acl.Action,
+		"is_active":    acl.IsActive,
+		"comment":      acl.Comment,
+	}
+	if err = ps.filterRepo.UpdateCommandFilter(ctx, aclID, values, req.Users, req.UserGroups); err != nil {
+		return nil, err
+	}
+	return ps.filterRepo.GetCommandFilter(ctx, aclID)
+}
+
+func (ps *PermissionService) DeleteCommandFilter(ctx context.Context, aclID uint) error {
+	return ps.filterRepo.DeleteCommandFilter(ctx, aclID)
+}
+
+// ListSessionCommandFilters 查询会话生效的命令过滤规则, 已按优先级排序
+func (jms *JMService) ListSessionCommandFilters(ctx context.Context, user *entity.User, asset *entity.Asset) (entity.CommandFilterACLs, error) {
+	acls, err := jms.filterRepo.ListUserCommandFilters(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	result := make(entity.CommandFilterACLs, 0, len(acls))
+	for _, acl := range acls {
+		if !acl.MatchAsset(asset) {
+			continue
+		}
+		if err = acl.Compile(); err != nil {
+			klog.Errorf("Compile command filter %s failed: %s", acl, err)
+			continue
+		}
+		result = append(result, acl)
+	}
+	result.Sort()
+	return result, nil
+}
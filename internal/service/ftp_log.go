@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"k8s.io/klog/v2"
+)
+
+const defaultFTPLogPageSize = 20
+
+func (jms *JMService) RecordFTPLog(ctx context.Context, log *entity.FTPLog) {
+	if err := jms.ftpLogRepo.CreateFTPLog(ctx, log); err != nil {
+		klog.Errorf("User %s record ftp log %s %s failed: %s", log.User, log.Operate, log.Path, err)
+	}
+}
+
+func (jms *JMService) ListFTPLogs(ctx context.Context, param *entity.FTPLogPaginationParam) (*entity.FTPLogListResponse, error) {
+	if param.PageSize <= 0 {
+		param.PageSize = defaultFTPLogPageSize
+	}
+	logs, count, err := jms.ftpLogRepo.ListFTPLogsWithPager(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.FTPLogListResponse{Total: count, Data: logs}, nil
+}
@@ -11,13 +11,27 @@ type JMService struct {
 	clusterRepo entity.ClusterRepo
 	podRepo     entity.PodRepo
 	userRepo    entity.UserRepo
+	permRepo    entity.AssetPermissionRepo
+	accessRepo  entity.AccessRequestRepo
+	commandRepo entity.CommandRepo
+	filterRepo  entity.CommandFilterRepo
+	sessionRepo entity.SessionRepo
+	ftpLogRepo  entity.FTPLogRepo
 }
 
-func NewJMService(clusterRepo entity.ClusterRepo, userRepo entity.UserRepo, podRepo entity.PodRepo) *JMService {
+func NewJMService(clusterRepo entity.ClusterRepo, userRepo entity.UserRepo, podRepo entity.PodRepo,
+	permRepo entity.AssetPermissionRepo, accessRepo entity.AccessRequestRepo, commandRepo entity.CommandRepo,
+	filterRepo entity.CommandFilterRepo, sessionRepo entity.SessionRepo, ftpLogRepo entity.FTPLogRepo) *JMService {
 	return &JMService{
 		clusterRepo: clusterRepo,
 		userRepo:    userRepo,
 		podRepo:     podRepo,
+		permRepo:    permRepo,
+		accessRepo:  accessRepo,
+		commandRepo: commandRepo,
+		filterRepo:  filterRepo,
+		sessionRepo: sessionRepo,
+		ftpLogRepo:  ftpLogRepo,
 	}
 }
 
@@ -42,7 +56,62 @@ func (jms *JMService) ListAssetByIP(ctx context.Context, podIP string) ([]*entit
 	return nil, nil
 }
 
-func (jms *JMService) ListPodAsset(ctx context.Context, podIP string) ([]*entity.Asset, error) {
+// userPermissions 管理员返回 nil 表示不受授权限制
+func (jms *JMService) userPermissions(ctx context.Context, user *entity.User) ([]*entity.AssetPermission, error) {
+	if user.IsAdmin() {
+		return nil, nil
+	}
+	perms, err := jms.permRepo.ListUserPermissions(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if perms == nil {
+		perms = make([]*entity.AssetPermission, 0)
+	}
+	return perms, nil
+}
+
+// ValidateAssetPermission 校验用户是否被授权访问该资产, 多条规则匹配时取最晚的过期时间,
+// 任一规则可写即可写, 上传、下载同理; 审计员始终为只读且不能上传
+func (jms *JMService) ValidateAssetPermission(ctx context.Context, user *entity.User, asset *entity.Asset) (entity.AssetAccess, bool, error) {
+	access := entity.AssetAccess{ReadOnly: user.IsAuditor()}
+	perms, err := jms.userPermissions(ctx, user)
+	if err != nil {
+		return access, false, err
+	}
+	if perms == nil {
+		access.ExpireAt = entity.ExpireNever
+		access.Upload = !access.ReadOnly
+		access.Download = true
+		return access, true, nil
+	}
+	var (
+		ok       bool
+		writable bool
+	)
+	for _, perm := range perms {
+		if perm.MatchAsset(asset) {
+			ok = true
+			if perm.ExpireAt() > access.ExpireAt {
+				access.ExpireAt = perm.ExpireAt()
+			}
+			if !perm.ReadOnly {
+				writable = true
+				access.Upload = access.Upload || perm.AllowUpload
+			}
+			access.Download = access.Download || perm.AllowDownload
+		}
+	}
+	if !writable {
+		access.ReadOnly = true
+	}
+	if access.ReadOnly {
+		access.Upload = false
+	}
+	return access, ok, nil
+}
+
+func (jms *JMService) ListPodAsset(ctx context.Context, user *entity.User, podIP string) ([]*entity.Asset, error) {
 	filter := &entity.Pod{
 		PodIP: podIP,
 	}
@@ -52,17 +121,78 @@ func (jms *JMService) ListPodAsset(ctx context.Context, podIP string) ([]*entity
 	if err != nil {
 		return nil, err
 	}
-	return utils.PodsToJumpAssets(podList), err
+	perms, err := jms.userPermissions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	return filterPermittedAssets(perms, utils.PodsToJumpAssets(podList)), nil
+}
+
+// ListUserPodAssets 返回用户有权访问的 Pod 资产, cluster 或 namespace 为空时不作为过滤条件
+func (jms *JMService) ListUserPodAssets(ctx context.Context, user *entity.User, cluster, namespace string) ([]*entity.Asset, error) {
+	perms, err := jms.userPermissions(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	assets, err := jms.FindPodAssets(ctx, cluster, namespace, "")
+	if err != nil {
+		return nil, err
+	}
+	return filterPermittedAssets(perms, assets), nil
+}
+
+// filterPermittedAssets perms 为 nil 时表示管理员, 不做过滤
+func filterPermittedAssets(perms []*entity.AssetPermission, assets []*entity.Asset) []*entity.Asset {
+	if perms == nil {
+		return assets
+	}
+	permitted := make([]*entity.Asset, 0, len(assets))
+	for _, asset := range assets {
+		for _, perm := range perms {
+			if perm.MatchAsset(asset) {
+				permitted = append(permitted, asset)
+				break
+			}
+		}
+	}
+	return permitted
 }
 
-func (jms *JMService) ListPodsFromStorage(ctx context.Context, param *entity.PaginationParam) (resp *entity.PaginationResponse, err error) {
+// FindPodAssets 按 集群/命名空间/Pod名称 查找资产, cluster 或 namespace 为空时不作为过滤条件
+func (jms *JMService) FindPodAssets(ctx context.Context, cluster, namespace, pod string) ([]*entity.Asset, error) {
+	filter := &entity.Pod{
+		Namespace: namespace,
+		PodName:   pod,
+	}
+	podList, err := jms.podRepo.ListPodsWithPreLoadCluster(ctx, filter, "cluster_ref desc")
+	if err != nil {
+		return nil, err
+	}
+	matched := make([]*entity.Pod, 0, len(podList))
+	for _, p := range podList {
+		if p.Cluster == nil {
+			continue
+		}
+		if cluster != "" && p.Cluster.ClusterName != cluster {
+			continue
+		}
+		matched = append(matched, p)
+	}
+	return utils.PodsToJumpAssets(matched), nil
+}
+
+func (jms *JMService) ListPodsFromStorage(ctx context.Context, user *entity.User, param *entity.PaginationParam) (resp *entity.PaginationResponse, err error) {
 	var (
 		filter = &entity.Pod{}
 		count  int
 		pods   []*entity.Pod
+		perms  []*entity.AssetPermission
 	)
 
-	pods, count, err = jms.podRepo.PreloadPodsWithPager(ctx, filter, param)
+	if perms, err = jms.userPermissions(ctx, user); err != nil {
+		return nil, err
+	}
+	pods, count, err = jms.podRepo.PreloadPodsWithPager(ctx, filter, param, perms)
 	if err != nil {
 		return nil, err
 	}
@@ -75,6 +205,18 @@ func (jms *JMService) ListPodsFromStorage(ctx context.Context, param *entity.Pag
 	return resp, err
 }
 
+// ListAssetContainers 查询 pod 下同步的容器列表
+func (jms *JMService) ListAssetContainers(ctx context.Context, asset *entity.Asset) ([]*entity.Container, error) {
+	filter := &entity.Pod{
+		PodName:   asset.PodName,
+		Namespace: asset.Namespace,
+	}
+	if asset.Cluster != nil {
+		filter.ClusterRef = asset.Cluster.UniqKey
+	}
+	return jms.podRepo.ListPodContainers(ctx, filter)
+}
+
 // ApplyK8sCluster create or update kubernetes cluster object
 func (jms *JMService) ApplyK8sCluster(ctx context.Context, cluster *entity.ClusterConfig) (*entity.ClusterConfig, error) {
 	var (
@@ -42,7 +42,7 @@ func (srv *kubeHandlerServices) newHandler(kind string, uniqKey string) *kubeHan
 }
 
 func (srv *kubeHandlerServices) buildKey(kind string, uniqKey string) string {
-	return fmt.Sprintf("%s_%d", kind, uniqKey)
+	return fmt.Sprintf("%s_%s", kind, uniqKey)
 }
 
 func (srv *kubeHandlerServices) loadHandler(kind string, uniqKey string) *kubeHandler {
@@ -178,6 +178,8 @@ func (kh *kubeHandler) sendEvent(obj interface{}, eventType string) {
 				ResourceKind: kh.resourceKind,
 				Status:       pods.PodStatus(pod),
 				PodIP:        pod.Status.PodIP,
+				Labels:       entity.EncodePodLabels(pod.Labels),
+				Containers:   podContainers(pod),
 			},
 			eventType: eventType,
 		})
@@ -206,3 +208,38 @@ func (kh *kubeHandler) OnUpdate(oldObj, newObj interface{}) {
 func (kh *kubeHandler) OnDelete(obj interface{}) {
 	kh.sendEvent(obj, EVENT_TYPE_DELETE)
 }
+
+// podContainers 提取 pod 中的业务容器及其状态, 不包含 init 容器
+func podContainers(pod *corev1.Pod) []*entity.Container {
+	statuses := make(map[string]corev1.ContainerStatus, len(pod.Status.ContainerStatuses))
+	for _, status := range pod.Status.ContainerStatuses {
+		statuses[status.Name] = status
+	}
+	containers := make([]*entity.Container, 0, len(pod.Spec.Containers))
+	for _, c := range pod.Spec.Containers {
+		container := &entity.Container{
+			ContainerName: c.Name,
+			Image:         c.Image,
+			Status:        "Unknown",
+		}
+		if status, ok := statuses[c.Name]; ok {
+			container.Ready = status.Ready
+			switch {
+			case status.State.Running != nil:
+				container.Status = "Running"
+			case status.State.Waiting != nil:
+				container.Status = "Waiting"
+				if status.State.Waiting.Reason != "" {
+					container.Status = status.State.Waiting.Reason
+				}
+			case status.State.Terminated != nil:
+				container.Status = "Terminated"
+				if status.State.Terminated.Reason != "" {
+					container.Status = status.State.Terminated.Reason
+				}
+			}
+		}
+		containers = append(containers, container)
+	}
+	return containers
+}
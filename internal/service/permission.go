@@ -0,0 +1,173 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+)
+
+type PermissionService struct {
+	permRepo      entity.AssetPermissionRepo
+	userGroupRepo entity.UserGroupRepo
+	userRepo      entity.UserRepo
+	filterRepo    entity.CommandFilterRepo
+}
+
+func NewPermissionService(permRepo entity.AssetPermissionRepo, userGroupRepo entity.UserGroupRepo,
+	userRepo entity.UserRepo, filterRepo entity.CommandFilterRepo) *PermissionService {
+	return &PermissionService{
+		permRepo:      permRepo,
+		userGroupRepo: userGroupRepo,
+		userRepo:      userRepo,
+		filterRepo:    filterRepo,
+	}
+}
+
+func (ps *PermissionService) checkUsers(ctx context.Context, userIDs []uint) error {
+	if len(userIDs) == 0 {
+		return nil
+	}
+	count, err := ps.userRepo.CountUsersByIDs(ctx, userIDs)
+	if err != nil {
+		return err
+	}
+	if count != len(userIDs) {
+		return fmt.Errorf("users %v contain not exist user", userIDs)
+	}
+	return nil
+}
+
+func (ps *PermissionService) checkUserGroups(ctx context.Context, groupIDs []uint) error {
+	if len(groupIDs) == 0 {
+		return nil
+	}
+	count, err := ps.userGroupRepo.CountUserGroupsByIDs(ctx, groupIDs)
+	if err != nil {
+		return err
+	}
+	if count != len(groupIDs) {
+		return fmt.Errorf("user groups %v contain not exist group", groupIDs)
+	}
+	return nil
+}
+
+func (ps *PermissionService) ListUserGroups(ctx context.Context) ([]*entity.UserGroup, error) {
+	return ps.userGroupRepo.ListUserGroups(ctx)
+}
+
+func (ps *PermissionService) CreateUserGroup(ctx context.Context, req *entity.UserGroupReq) (*entity.UserGroup, error) {
+	if err := ps.checkUsers(ctx, req.Users); err != nil {
+		return nil, err
+	}
+	group := &entity.UserGroup{
+		Name:    req.Name,
+		Comment: req.Comment,
+	}
+	if err := ps.userGroupRepo.CreateUserGroup(ctx, group, req.Users); err != nil {
+		return nil, err
+	}
+	return ps.userGroupRepo.GetUserGroup(ctx, group.ID)
+}
+
+// UpdateUserGroup 请求中 users 为 null 时保留原有成员
+func (ps *PermissionService) UpdateUserGroup(ctx context.Context, groupID uint, req *entity.UserGroupReq) (*entity.UserGroup, error) {
+	if _, err := ps.userGroupRepo.GetUserGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+	if err := ps.checkUsers(ctx, req.Users); err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{
+		"name":    req.Name,
+		"comment": req.Comment,
+	}
+	if err := ps.userGroupRepo.UpdateUserGroup(ctx, groupID, values, req.Users); err != nil {
+		return nil, err
+	}
+	return ps.userGroupRepo.GetUserGroup(ctx, groupID)
+}
+
+func (ps *PermissionService) DeleteUserGroup(ctx context.Context, groupID uint) error {
+	return ps.userGroupRepo.DeleteUserGroup(ctx, groupID)
+}
+
+func (ps *PermissionService) ListPermissions(ctx context.Context) ([]*entity.AssetPermission, error) {
+	return ps.permRepo.ListPermissions(ctx)
+}
+
+func (ps *PermissionService) buildPermission(ctx context.Context, req *entity.AssetPermissionReq) (*entity.AssetPermission, error) {
+	if err := ps.checkUsers(ctx, req.Users); err != nil {
+		return nil, err
+	}
+	if err := ps.checkUserGroups(ctx, req.UserGroups); err != nil {
+		return nil, err
+	}
+	perm := &entity.AssetPermission{
+		Name:          req.Name,
+		ClusterName:   req.ClusterName,
+		Namespace:     req.Namespace,
+		PodName:       req.PodName,
+		PodSelector:   req.PodSelector,
+		IsActive:      true,
+		ReadOnly:      req.ReadOnly,
+		AllowUpload:   req.AllowUpload,
+		AllowDownload: req.AllowDownload,
+		DateStart:     req.DateStart,
+		DateExpired:   req.DateExpired,
+		Comment:       req.Comment,
+	}
+	if req.IsActive != nil {
+		perm.IsActive = *req.IsActive
+	}
+	if perm.DateStart != nil && perm.DateExpired != nil && !perm.DateExpired.After(*perm.DateStart) {
+		return nil, fmt.Errorf("date_expired must be after date_start")
+	}
+	if _, err := perm.LabelRequirements(); err != nil {
+		return nil, fmt.Errorf("invalid pod selector %q: %w", perm.PodSelector, err)
+	}
+	return perm, nil
+}
+
+func (ps *PermissionService) CreatePermission(ctx context.Context, req *entity.AssetPermissionReq) (*entity.AssetPermission, error) {
+	perm, err := ps.buildPermission(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	if err = ps.permRepo.CreatePermission(ctx, perm, req.Users, req.UserGroups); err != nil {
+		return nil, err
+	}
+	return ps.permRepo.GetPermission(ctx, perm.ID)
+}
+
+// UpdatePermission 请求中 users、user_groups 为 null 时保留原有授权对象
+func (ps *PermissionService) UpdatePermission(ctx context.Context, permID uint, req *entity.AssetPermissionReq) (*entity.AssetPermission, error) {
+	if _, err := ps.permRepo.GetPermission(ctx, permID); err != nil {
+		return nil, err
+	}
+	perm, err := ps.buildPermission(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]interface{}{
+		"name":           perm.Name,
+		"cluster_name":   perm.ClusterName,
+		"namespace":      perm.Namespace,
+		"pod_name":       perm.PodName,
+		"pod_selector":   perm.PodSelector,
+		"is_active":      perm.IsActive,
+		"read_only":      perm.ReadOnly,
+		"allow_upload":   perm.AllowUpload,
+		"allow_download": perm.AllowDownload,
+		"date_start":     perm.DateStart,
+		"date_expired":   perm.DateExpired,
+		"comment":        perm.Comment,
+	}
+	if err = ps.permRepo.UpdatePermission(ctx, permID, values, req.Users, req.UserGroups); err != nil {
+		return nil, err
+	}
+	return ps.permRepo.GetPermission(ctx, permID)
+}
+
+func (ps *PermissionService) DeletePermission(ctx context.Context, permID uint) error {
+	return ps.permRepo.DeletePermission(ctx, permID)
+}
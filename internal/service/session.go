@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"k8s.io/klog/v2"
+)
+
+const defaultSessionPageSize = 20
+
+func (jms *JMService) CreateSession(ctx context.Context, sess *entity.Session) {
+	if err := jms.sessionRepo.CreateSession(ctx, sess); err != nil {
+		klog.Errorf("Session[%s] create session record failed: %s", sess.ID, err)
+	}
+}
+
+func (jms *JMService) FinishSession(ctx context.Context, sess *entity.Session) {
+	if err := jms.sessionRepo.FinishSession(ctx, sess); err != nil {
+		klog.Errorf("Session[%s] finish session record failed: %s", sess.ID, err)
+	}
+}
+
+func (jms *JMService) ListSessionHistory(ctx context.Context, param *entity.SessionPaginationParam) (*entity.SessionListResponse, error) {
+	if param.PageSize <= 0 {
+		param.PageSize = defaultSessionPageSize
+	}
+	sessions, count, err := jms.sessionRepo.ListSessionsWithPager(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.SessionListResponse{Total: count, Data: sessions}, nil
+}
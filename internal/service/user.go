@@ -2,16 +2,23 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/config"
 	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"k8s.io/klog/v2"
+	"time"
 )
 
 type UserService struct {
-	userRepo entity.UserRepo
+	userRepo       entity.UserRepo
+	loginBlockRepo entity.LoginBlockRepo
 }
 
-func NewUserService(userRepo entity.UserRepo) *UserService {
+func NewUserService(userRepo entity.UserRepo, loginBlockRepo entity.LoginBlockRepo) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:       userRepo,
+		loginBlockRepo: loginBlockRepo,
 	}
 }
 
@@ -24,7 +31,215 @@ func (us *UserService) GetUserInfoByName(ctx context.Context, username string) (
 	return user, err
 }
 
-//func (us *UserService) ApplyUser(ctx context.Context, user *entity.User) (*entity.User, error) {
-//
-//	//us.userRepo
-//}
+func (us *UserService) ListUserPublicKeys(ctx context.Context, userID uint) ([]*entity.UserPublicKey, error) {
+	return us.userRepo.ListPublicKeys(ctx, userID)
+}
+
+// RehashPassword 将历史明文密码替换为哈希值
+func (us *UserService) RehashPassword(ctx context.Context, user *entity.User, password string) error {
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	if err = us.userRepo.UpdatePassword(ctx, user.ID, hashed); err != nil {
+		return err
+	}
+	user.Password = hashed
+	return nil
+}
+
+// BindOTPSecret 保存用户已确认的 TOTP 密钥
+func (us *UserService) BindOTPSecret(ctx context.Context, user *entity.User, secret string) error {
+	if err := us.userRepo.UpdateOTPSecret(ctx, user.ID, secret); err != nil {
+		return err
+	}
+	user.OTPSecretKey = secret
+	return nil
+}
+
+// BootstrapAdmins 将配置中指定的用户设置为管理员, 已有用户在引入角色后默认都是普通用户
+func (us *UserService) BootstrapAdmins(ctx context.Context, usernames []string) {
+	for _, username := range usernames {
+		user, err := us.GetUserInfoByName(ctx, username)
+		if err != nil {
+			klog.Errorf("Bootstrap admin %s failed: %s", username, err)
+			continue
+		}
+		if user.IsAdmin() {
+			continue
+		}
+		if err = us.userRepo.UpdateUser(ctx, user.ID, map[string]interface{}{"role": entity.RoleAdmin}); err != nil {
+			klog.Errorf("Bootstrap admin %s failed: %s", username, err)
+			continue
+		}
+		klog.Infof("Bootstrap user %s as admin", user)
+	}
+}
+
+const defaultUserPageSize = 20
+
+func (us *UserService) GetUserByID(ctx context.Context, userID uint) (*entity.User, error) {
+	filter := &entity.User{BaseModel: entity.BaseModel{ID: userID}}
+	return us.userRepo.GetInfoByID(ctx, filter)
+}
+
+func (us *UserService) CreateUser(ctx context.Context, req *entity.UserCreateReq) (*entity.User, error) {
+	if _, err := us.GetUserInfoByName(ctx, req.Username); err == nil {
+		return nil, fmt.Errorf("user %s already exists", req.Username)
+	}
+
+	hashed, err := utils.HashPassword(req.Password)
+	if err != nil {
+		return nil, err
+	}
+	user := &entity.User{
+		Name:     req.Name,
+		Username: req.Username,
+		Email:    req.Email,
+		Password: hashed,
+		Role:     entity.RoleUser,
+		IsActive: true,
+		OTPLevel: req.OTPLevel,
+	}
+	if req.Role != "" {
+		user.Role = req.Role
+	}
+	if req.IsActive != nil {
+		user.IsActive = *req.IsActive
+	}
+	for i := range req.PublicKeys {
+		user.PublicKeys = append(user.PublicKeys, &entity.UserPublicKey{PublicKey: req.PublicKeys[i]})
+	}
+
+	err = us.userRepo.CreateUser(ctx, user)
+	return user, err
+}
+
+func (us *UserService) UpdateUser(ctx context.Context, userID uint, req *entity.UserUpdateReq) (*entity.User, error) {
+	if _, err := us.GetUserByID(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	values := make(map[string]interface{})
+	if req.Name != nil {
+		values["name"] = *req.Name
+	}
+	if req.Email != nil {
+		values["email"] = *req.Email
+	}
+	if req.Role != nil {
+		values["role"] = *req.Role
+	}
+	if req.IsActive != nil {
+		values["is_active"] = *req.IsActive
+	}
+	if req.OTPLevel != nil {
+		values["otp_level"] = *req.OTPLevel
+		if *req.OTPLevel == entity.OTPLevelDisabled {
+			values["otp_secret_key"] = ""
+		}
+	}
+	if err := us.userRepo.UpdateUser(ctx, userID, values); err != nil {
+		return nil, err
+	}
+
+	if req.PublicKeys != nil {
+		keys := make([]*entity.UserPublicKey, 0, len(req.PublicKeys))
+		for i := range req.PublicKeys {
+			keys = append(keys, &entity.UserPublicKey{PublicKey: req.PublicKeys[i]})
+		}
+		if err := us.userRepo.ReplacePublicKeys(ctx, userID, keys); err != nil {
+			return nil, err
+		}
+	}
+	return us.GetUserByID(ctx, userID)
+}
+
+func (us *UserService) ListUsers(ctx context.Context, param *entity.UserPaginationParam) (*entity.UserListResponse, error) {
+	if param.PageSize <= 0 {
+		param.PageSize = defaultUserPageSize
+	}
+	users, count, err := us.userRepo.ListUsersWithPager(ctx, param)
+	if err != nil {
+		return nil, err
+	}
+	return &entity.UserListResponse{Total: count, Data: users}, nil
+}
+
+func (us *UserService) DisableUser(ctx context.Context, userID uint) error {
+	if _, err := us.GetUserByID(ctx, userID); err != nil {
+		return err
+	}
+	return us.userRepo.UpdateUser(ctx, userID, map[string]interface{}{"is_active": false})
+}
+
+func loginBlockUserKey(username string) string {
+	return "user:" + username
+}
+
+func loginBlockIPKey(remoteAddr string) string {
+	return "ip:" + remoteAddr
+}
+
+// IsLoginBlocked 用户或来源 IP 处于锁定期内时拒绝登录
+func (us *UserService) IsLoginBlocked(ctx context.Context, username, remoteAddr string) bool {
+	return us.loginBlockRepo.IsBlocked(ctx, loginBlockUserKey(username)) ||
+		us.loginBlockRepo.IsBlocked(ctx, loginBlockIPKey(remoteAddr))
+}
+
+// RecordLoginFailed 统计窗口内的连续失败次数, 超过阈值后分别锁定用户和来源 IP
+func (us *UserService) RecordLoginFailed(ctx context.Context, username, remoteAddr string) {
+	conf := config.GetConf()
+	if conf.LoginFailedLimit <= 0 {
+		return
+	}
+	window := time.Duration(conf.LoginFailedWindow) * time.Minute
+	blockTime := time.Duration(conf.LoginBlockTime) * time.Minute
+	// 来源 IP 可能在窗口结束时被锁定, 记录保留到锁定结束
+	us.loginBlockRepo.AddFailedSource(ctx, loginBlockUserKey(username), remoteAddr, window+blockTime)
+	for _, key := range []string{loginBlockUserKey(username), loginBlockIPKey(remoteAddr)} {
+		count, err := us.loginBlockRepo.IncrFailedCount(ctx, key, window)
+		if err != nil {
+			klog.Errorf("Record login failed %s err: %s", key, err)
+			continue
+		}
+		if count >= conf.LoginFailedLimit {
+			us.loginBlockRepo.Block(ctx, key, blockTime)
+			us.loginBlockRepo.ClearFailedCount(ctx, key)
+			klog.Warningf("Login failed %d times, block %s for %s", count, key, blockTime)
+		}
+	}
+}
+
+func (us *UserService) ClearLoginFailed(ctx context.Context, username, remoteAddr string) {
+	us.loginBlockRepo.ClearFailedCount(ctx, loginBlockUserKey(username))
+	us.loginBlockRepo.ClearFailedCount(ctx, loginBlockIPKey(remoteAddr))
+}
+
+// UnlockUser 解除用户锁定, 同时解除该用户登录失败过的来源 IP 的锁定
+func (us *UserService) UnlockUser(ctx context.Context, userID uint) error {
+	user, err := us.GetUserByID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	userKey := loginBlockUserKey(user.Username)
+	us.loginBlockRepo.Unblock(ctx, userKey)
+	us.loginBlockRepo.ClearFailedCount(ctx, userKey)
+	// 同一来源的连续失败也会锁定来源 IP, 一并解锁该用户失败过的来源
+	for _, remoteAddr := range us.loginBlockRepo.PopFailedSources(ctx, userKey) {
+		us.loginBlockRepo.Unblock(ctx, loginBlockIPKey(remoteAddr))
+		us.loginBlockRepo.ClearFailedCount(ctx, loginBlockIPKey(remoteAddr))
+	}
+	return nil
+}
+
+func (us *UserService) ResetPassword(ctx context.Context, userID uint, password string) error {
+	if _, err := us.GetUserByID(ctx, userID); err != nil {
+		return err
+	}
+	hashed, err := utils.HashPassword(password)
+	if err != nil {
+		return err
+	}
+	return us.userRepo.UpdatePassword(ctx, userID, hashed)
+}
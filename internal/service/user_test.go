@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+)
+
+type fakeUserRepo struct {
+	entity.UserRepo
+	users map[uint]*entity.User
+}
+
+func (r *fakeUserRepo) GetInfoByID(_ context.Context, filter *entity.User) (*entity.User, error) {
+	if user, ok := r.users[filter.ID]; ok {
+		return user, nil
+	}
+	return nil, errors.New("record not found")
+}
+
+// fakeLoginBlockRepo 内存实现, 不处理过期时间
+type fakeLoginBlockRepo struct {
+	counts  map[string]int
+	blocked map[string]bool
+	sources map[string][]string
+}
+
+func newFakeLoginBlockRepo() *fakeLoginBlockRepo {
+	return &fakeLoginBlockRepo{
+		counts:  make(map[string]int),
+		blocked: make(map[string]bool),
+		sources: make(map[string][]string),
+	}
+}
+
+func (r *fakeLoginBlockRepo) IncrFailedCount(_ context.Context, key string, _ time.Duration) (int, error) {
+	r.counts[key]++
+	return r.counts[key], nil
+}
+
+func (r *fakeLoginBlockRepo) ClearFailedCount(_ context.Context, key string) {
+	delete(r.counts, key)
+}
+
+func (r *fakeLoginBlockRepo) Block(_ context.Context, key string, _ time.Duration) {
+	r.blocked[key] = true
+}
+
+func (r *fakeLoginBlockRepo) IsBlocked(_ context.Context, key string) bool {
+	return r.blocked[key]
+}
+
+func (r *fakeLoginBlockRepo) Unblock(_ context.Context, key string) {
+	delete(r.blocked, key)
+}
+
+func (r *fakeLoginBlockRepo) AddFailedSource(_ context.Context, key, source string, _ time.Duration) {
+	for _, item := range r.sources[key] {
+		if item == source {
+			return
+		}
+	}
+	r.sources[key] = append(r.sources[key], source)
+}
+
+func (r *fakeLoginBlockRepo) PopFailedSources(_ context.Context, key string) []string {
+	sources := r.sources[key]
+	delete(r.sources, key)
+	return sources
+}
+
+const (
+	testAddr      = "10.0.0.1"
+	testOtherAddr = "10.0.0.2"
+)
+
+// loginAttempt 依次执行的登录操作, ok 为 true 表示登录成功, unlock 表示管理员解锁 user
+type loginAttempt struct {
+	user   string
+	addr   string
+	ok     bool
+	unlock bool
+}
+
+func TestLoginBlock(t *testing.T) {
+	fail := func(user, addr string) loginAttempt { return loginAttempt{user: user, addr: addr} }
+	tests := []struct {
+		name     string
+		limit    int
+		attempts []loginAttempt
+		// 最后检查的用户、来源及期望的锁定状态
+		user, addr string
+		want       bool
+	}{
+		{
+			name:     "below limit",
+			limit:    3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr)},
+			user:     "alice", addr: testAddr, want: false,
+		},
+		{
+			name:     "reach limit",
+			limit:    3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr), fail("alice", testAddr)},
+			user:     "alice", addr: testOtherAddr, want: true,
+		},
+		{
+			name:     "blocked source rejects other users",
+			limit:    3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("bob", testAddr), fail("carol", testAddr)},
+			user:     "dave", addr: testAddr, want: true,
+		},
+		{
+			name:  "success clears failures",
+			limit: 3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr),
+				{user: "alice", addr: testAddr, ok: true}, fail("alice", testAddr), fail("alice", testAddr)},
+			user: "alice", addr: testAddr, want: false,
+		},
+		{
+			name:  "unlock clears user and source",
+			limit: 3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr), fail("alice", testAddr),
+				{user: "alice", unlock: true}},
+			user: "alice", addr: testAddr, want: false,
+		},
+		{
+			name:  "unlock resets failure counters",
+			limit: 3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr), fail("alice", testAddr),
+				{user: "alice", unlock: true}, fail("alice", testAddr)},
+			user: "alice", addr: testAddr, want: false,
+		},
+		{
+			name:  "unlock keeps sources the user never failed from",
+			limit: 3,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr), fail("alice", testAddr),
+				fail("bob", testOtherAddr), fail("carol", testOtherAddr), fail("dave", testOtherAddr),
+				{user: "alice", unlock: true}},
+			user: "alice", addr: testOtherAddr, want: true,
+		},
+		{
+			name:     "no limit",
+			limit:    0,
+			attempts: []loginAttempt{fail("alice", testAddr), fail("alice", testAddr), fail("alice", testAddr)},
+			user:     "alice", addr: testAddr, want: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config.GlobalConfig = &config.Config{
+				LoginFailedLimit:  tt.limit,
+				LoginFailedWindow: 10,
+				LoginBlockTime:    30,
+			}
+			defer func() { config.GlobalConfig = nil }()
+			users := map[uint]*entity.User{}
+			userIDs := map[string]uint{}
+			for i, name := range []string{"alice", "bob", "carol", "dave"} {
+				id := uint(i + 1)
+				users[id] = &entity.User{BaseModel: entity.BaseModel{ID: id}, Username: name}
+				userIDs[name] = id
+			}
+			us := NewUserService(&fakeUserRepo{users: users}, newFakeLoginBlockRepo())
+			ctx := context.Background()
+			for _, attempt := range tt.attempts {
+				switch {
+				case attempt.unlock:
+					if err := us.UnlockUser(ctx, userIDs[attempt.user]); err != nil {
+						t.Fatal(err)
+					}
+				case attempt.ok:
+					us.ClearLoginFailed(ctx, attempt.user, attempt.addr)
+				default:
+					us.RecordLoginFailed(ctx, attempt.user, attempt.addr)
+				}
+			}
+			if got := us.IsLoginBlocked(ctx, tt.user, tt.addr); got != tt.want {
+				t.Errorf("IsLoginBlocked(%s, %s) = %v, want %v", tt.user, tt.addr, got, tt.want)
+			}
+		})
+	}
+}
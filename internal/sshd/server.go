@@ -4,6 +4,7 @@ import (
 	"context"
 	"github.com/gliderlabs/ssh"
 	"github.com/pires/go-proxyproto"
+	gossh "golang.org/x/crypto/ssh"
 	"k8s.io/klog/v2"
 	"net"
 	"time"
@@ -34,7 +35,12 @@ type SSHHandler interface {
 	LocalPortForwardingPermission(ctx ssh.Context, destinationHost string, destinationPort uint32) bool
 	GetSSHSigner() ssh.Signer
 	PasswordAuth(ctx ssh.Context, password string) bool
+	PublicKeyAuth(ctx ssh.Context, key ssh.PublicKey) bool
+	KeyboardInteractiveAuth(ctx ssh.Context, challenger gossh.KeyboardInteractiveChallenge) bool
 	SessionHandler(ssh.Session)
+	SFTPHandler(ssh.Session)
+	AuthLogCallback(ctx ssh.Context, conn gossh.ConnMetadata, method string, err error)
+	DirectTCPIPHandler(srv *ssh.Server, conn *gossh.ServerConn, newChan gossh.NewChannel, ctx ssh.Context)
 	GetSSHAddr() string
 }
 
@@ -42,14 +48,33 @@ func NewSshServer(handler SSHHandler) *Server {
 	srv := &ssh.Server{
 
 		Addr: handler.GetSSHAddr(),
-		//LocalPortForwardingCallback: func(ctx ssh.Context, destinationHost string, destinationPort uint32) bool {
-		//	return handler.LocalPortForwardingPermission(ctx, destinationHost, destinationPort)
-		//},
-		PasswordHandler: handler.PasswordAuth,
+		LocalPortForwardingCallback: func(ctx ssh.Context, destinationHost string, destinationPort uint32) bool {
+			return handler.LocalPortForwardingPermission(ctx, destinationHost, destinationPort)
+		},
+		PasswordHandler:  handler.PasswordAuth,
+		PublicKeyHandler: handler.PublicKeyAuth,
+
+		KeyboardInteractiveHandler: handler.KeyboardInteractiveAuth,
+		ServerConfigCallback: func(ctx ssh.Context) *gossh.ServerConfig {
+			// AuthLogCallback 在公钥请求完成验签后才会收到成功结果
+			return &gossh.ServerConfig{
+				AuthLogCallback: func(conn gossh.ConnMetadata, method string, err error) {
+					handler.AuthLogCallback(ctx, conn, method, err)
+				},
+			}
+		},
 
 		HostSigners: []ssh.Signer{handler.GetSSHSigner()},
 
 		Handler: handler.SessionHandler,
+		SubsystemHandlers: map[string]ssh.SubsystemHandler{
+			"sftp": handler.SFTPHandler,
+		},
+		// 自定义通道处理后需要显式保留默认的 session 处理
+		ChannelHandlers: map[string]ssh.ChannelHandler{
+			"session":      ssh.DefaultSessionHandler,
+			"direct-tcpip": handler.DirectTCPIPHandler,
+		},
 	}
 	return &Server{Srv: srv}
 }
@@ -29,9 +29,49 @@ func RegisterWebHandler(handler *httpd.Server) {
 
 	jumpGroup.Handle(http.MethodGet, "health", handler.HealthCheck)
 
+	// 除健康检查外的管理接口都需要 admin 认证
+	jumpGroup.Use(handler.AdminAuth)
+
 	jumpGroup.Handle(http.MethodPost, "k8s_cluster", handler.ApplyK8sCluster)
 
-	jumpGroup.Handle(http.MethodPost, "user", handler.ApplyK8sCluster)
+	userGroup := jumpGroup.Group("/users")
+	userGroup.Handle(http.MethodGet, "", handler.ListUsers)
+	userGroup.Handle(http.MethodPost, "", handler.CreateUser)
+	userGroup.Handle(http.MethodPut, ":id", handler.UpdateUser)
+	userGroup.Handle(http.MethodPost, ":id/disable", handler.DisableUser)
+	userGroup.Handle(http.MethodPost, ":id/unlock", handler.UnlockUser)
+	userGroup.Handle(http.MethodPost, ":id/reset_password", handler.ResetUserPassword)
+
+	userGroupGroup := jumpGroup.Group("/user_groups")
+	userGroupGroup.Handle(http.MethodGet, "", handler.ListUserGroups)
+	userGroupGroup.Handle(http.MethodPost, "", handler.CreateUserGroup)
+	userGroupGroup.Handle(http.MethodPut, ":id", handler.UpdateUserGroup)
+	userGroupGroup.Handle(http.MethodDelete, ":id", handler.DeleteUserGroup)
+
+	permGroup := jumpGroup.Group("/asset_permissions")
+	permGroup.Handle(http.MethodGet, "", handler.ListPermissions)
+	permGroup.Handle(http.MethodPost, "", handler.CreatePermission)
+	permGroup.Handle(http.MethodPut, ":id", handler.UpdatePermission)
+	permGroup.Handle(http.MethodDelete, ":id", handler.DeletePermission)
+
+	accessGroup := jumpGroup.Group("/access_requests")
+	accessGroup.Handle(http.MethodGet, "", handler.ListAccessRequests)
+	accessGroup.Handle(http.MethodPost, ":id/approve", handler.ApproveAccessRequest)
+	accessGroup.Handle(http.MethodPost, ":id/reject", handler.RejectAccessRequest)
+
+	jumpGroup.Handle(http.MethodGet, "commands", handler.ListCommands)
+	jumpGroup.Handle(http.MethodGet, "ftp_logs", handler.ListFTPLogs)
+
+	filterGroup := jumpGroup.Group("/command_filters")
+	filterGroup.Handle(http.MethodGet, "", handler.ListCommandFilters)
+	filterGroup.Handle(http.MethodPost, "", handler.CreateCommandFilter)
+	filterGroup.Handle(http.MethodPut, ":id", handler.UpdateCommandFilter)
+	filterGroup.Handle(http.MethodDelete, ":id", handler.DeleteCommandFilter)
+
+	sessionGroup := jumpGroup.Group("/sessions")
+	sessionGroup.Handle(http.MethodGet, "", handler.ListSessions)
+	sessionGroup.Handle(http.MethodGet, "history", handler.ListSessionHistory)
+	sessionGroup.Handle(http.MethodPost, ":id/tasks", handler.CreateSessionTask)
 
 	conf := config.GetConf()
 	addr := net.JoinHostPort(conf.BindHost, conf.HTTPPort)
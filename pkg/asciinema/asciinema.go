@@ -0,0 +1,184 @@
+package asciinema
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// asciicast v2 格式: https://docs.asciinema.org/manual/asciicast/v2/
+
+const (
+	version      = 2
+	defaultShell = "/bin/bash"
+	defaultTerm  = "xterm"
+)
+
+const (
+	EventOutput = "o"
+	EventInput  = "i"
+	EventResize = "r"
+)
+
+type Config struct {
+	Title     string
+	EnvShell  string
+	EnvTerm   string
+	Width     int
+	Height    int
+	Timestamp time.Time
+}
+
+type Option func(options *Config)
+
+func WithWidth(width int) Option {
+	return func(options *Config) {
+		options.Width = width
+	}
+}
+
+func WithHeight(height int) Option {
+	return func(options *Config) {
+		options.Height = height
+	}
+}
+
+func WithTimestamp(timestamp time.Time) Option {
+	return func(options *Config) {
+		options.Timestamp = timestamp
+	}
+}
+
+func WithTitle(title string) Option {
+	return func(options *Config) {
+		options.Title = title
+	}
+}
+
+func WithEnvShell(shell string) Option {
+	return func(options *Config) {
+		options.EnvShell = shell
+	}
+}
+
+func WithEnvTerm(term string) Option {
+	return func(options *Config) {
+		options.EnvTerm = term
+	}
+}
+
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	conf := Config{
+		EnvShell:  defaultShell,
+		EnvTerm:   defaultTerm,
+		Width:     80,
+		Height:    40,
+		Timestamp: time.Now(),
+	}
+	for _, setter := range opts {
+		setter(&conf)
+	}
+	return &Writer{
+		Config: conf,
+		writer: w,
+	}
+}
+
+type Writer struct {
+	Config
+	writer io.Writer
+	mu     sync.Mutex
+
+	rowMu   sync.Mutex
+	pending []byte // 上一次输出末尾不完整的 utf8 字符
+}
+
+type header struct {
+	Version   int               `json:"version"`
+	Width     int               `json:"width"`
+	Height    int               `json:"height"`
+	Timestamp int64             `json:"timestamp"`
+	Title     string            `json:"title,omitempty"`
+	Env       map[string]string `json:"env"`
+}
+
+func (w *Writer) WriteHeader() error {
+	p, err := json.Marshal(header{
+		Version:   version,
+		Width:     w.Width,
+		Height:    w.Height,
+		Timestamp: w.Timestamp.Unix(),
+		Title:     w.Title,
+		Env: map[string]string{
+			"SHELL": w.EnvShell,
+			"TERM":  w.EnvTerm,
+		},
+	})
+	if err != nil {
+		return err
+	}
+	return w.writeLine(p)
+}
+
+// WriteRow 记录终端输出, 被拆分在两次读取中的多字节字符留到下一次输出时再记录
+func (w *Writer) WriteRow(p []byte) error {
+	w.rowMu.Lock()
+	defer w.rowMu.Unlock()
+	if len(w.pending) > 0 {
+		p = append(w.pending, p...)
+		w.pending = nil
+	}
+	p, tail := splitIncompleteRune(p)
+	if len(tail) > 0 {
+		w.pending = append([]byte{}, tail...)
+	}
+	if len(p) == 0 {
+		return nil
+	}
+	return w.WriteEvent(time.Now(), EventOutput, string(p))
+}
+
+// splitIncompleteRune 拆出末尾不完整的多字节字符
+func splitIncompleteRune(p []byte) ([]byte, []byte) {
+	for i := 1; i < utf8.UTFMax && i <= len(p); i++ {
+		b := p[len(p)-i]
+		if !utf8.RuneStart(b) {
+			continue
+		}
+		if b >= utf8.RuneSelf && !utf8.FullRune(p[len(p)-i:]) {
+			return p[:len(p)-i], p[len(p)-i:]
+		}
+		break
+	}
+	return p, nil
+}
+
+// WriteResize 记录窗口大小变化
+func (w *Writer) WriteResize(width, height int) error {
+	return w.WriteEvent(time.Now(), EventResize, fmt.Sprintf("%dx%d", width, height))
+}
+
+func (w *Writer) WriteEvent(now time.Time, event, data string) error {
+	elapsed := now.Sub(w.Timestamp).Seconds()
+	if elapsed < 0 {
+		elapsed = 0
+	}
+	p, err := json.Marshal([]interface{}{elapsed, event, data})
+	if err != nil {
+		return err
+	}
+	return w.writeLine(p)
+}
+
+func (w *Writer) writeLine(p []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if _, err := w.writer.Write(p); err != nil {
+		return err
+	}
+	_, err := w.writer.Write([]byte("\n"))
+	return err
+}
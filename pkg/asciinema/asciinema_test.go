@@ -0,0 +1,43 @@
+package asciinema
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestWriteRowSplitRune(t *testing.T) {
+	tests := []struct {
+		name   string
+		chunks []string
+		want   []string
+	}{
+		{name: "ascii", chunks: []string{"ls\r\n", "a b\r\n"}, want: []string{"ls\r\n", "a b\r\n"}},
+		{name: "split 3 bytes", chunks: []string{"中\xe6", "\x96\x87"}, want: []string{"中", "文"}},
+		{name: "split 4 bytes", chunks: []string{"\xf0\x9f", "\x98", "\x80!"}, want: []string{"😀!"}},
+		{name: "invalid byte", chunks: []string{"a\xff", "b"}, want: []string{"a�", "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := NewWriter(&buf)
+			for _, chunk := range tt.chunks {
+				if err := w.WriteRow([]byte(chunk)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				var row []interface{}
+				if err := json.Unmarshal([]byte(line), &row); err != nil {
+					t.Fatal(err)
+				}
+				got = append(got, row[2].(string))
+			}
+			if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Fatalf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
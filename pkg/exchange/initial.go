@@ -1,41 +1,70 @@
 package exchange
 
+import (
+	"errors"
+	"github.com/daicheng123/kubejump/config"
+	"k8s.io/klog/v2"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
 var manager RoomManager
 
+// ErrSessionNotFound 所有实例上都没有找到会话
+var ErrSessionNotFound = errors.New("session not found")
+
+// SessionTaskHandler 在本实例执行会话任务, 会话不在本实例时 found 为 false
+type SessionTaskHandler func(sid string, task []byte) (found bool, err error)
+
+var sessionTaskHandler SessionTaskHandler
+
+func SetSessionTaskHandler(handler SessionTaskHandler) {
+	sessionTaskHandler = handler
+}
+
+func handleSessionTask(sid string, task []byte) (bool, error) {
+	if sessionTaskHandler == nil {
+		return false, nil
+	}
+	return sessionTaskHandler(sid, task)
+}
+
 func Initial() {
-	manager = newLocalManager()
-	//switch strings.ToLower(conf.ShareRoomType) {
-	//case "redis":
-	//	existFile := func(path string) string {
-	//		if info, err2 := os.Stat(path); err2 == nil && !info.IsDir() {
-	//			return path
-	//		}
-	//		return ""
-	//	}
-	//	sslCaPath := filepath.Join(conf.CertsFolderPath, "redis_ca.crt")
-	//	sslCertPath := filepath.Join(conf.CertsFolderPath, "redis_client.crt")
-	//	sslKeyPath := filepath.Join(conf.CertsFolderPath, "redis_client.key")
-	//	manager, err = newRedisManager(Config{
-	//		Addr:     net.JoinHostPort(conf.RedisHost, conf.RedisPort),
-	//		Password: conf.RedisPassword,
-	//		Clusters: conf.RedisClusters,
-	//		DBIndex:  conf.RedisDBIndex,
-	//
-	//		SentinelPassword: conf.RedisSentinelPassword,
-	//		SentinelsHost:    conf.RedisSentinelHosts,
-	//		UseSSL:           conf.RedisUseSSL,
-	//		SSLCa:            existFile(sslCaPath),
-	//		SSLCert:          existFile(sslCertPath),
-	//		SSLKey:           existFile(sslKeyPath),
-	//	})
-	//
-	//default:
-	//manager = newLocalManager()
-	//}
-	//logger.Infof("Exchange share room type: %s", conf.ShareRoomType)
-	//if err != nil {
-	//	logger.Fatal(err)
-	//}
+	var err error
+	conf := config.GetConf()
+	switch strings.ToLower(conf.ShareRoomType) {
+	case "redis":
+		existFile := func(path string) string {
+			if info, err2 := os.Stat(path); err2 == nil && !info.IsDir() {
+				return path
+			}
+			return ""
+		}
+		sslCaPath := filepath.Join(conf.CertsFolderPath, "redis_ca.crt")
+		sslCertPath := filepath.Join(conf.CertsFolderPath, "redis_client.crt")
+		sslKeyPath := filepath.Join(conf.CertsFolderPath, "redis_client.key")
+		manager, err = newRedisManager(Config{
+			Addr:     net.JoinHostPort(conf.RedisHost, conf.RedisPort),
+			Password: conf.RedisPassword,
+			Clusters: conf.RedisClusters,
+			DBIndex:  conf.RedisDBIndex,
+
+			SentinelPassword: conf.RedisSentinelPassword,
+			SentinelsHost:    conf.RedisSentinelHosts,
+			UseSSL:           conf.RedisUseSSL,
+			SSLCa:            existFile(sslCaPath),
+			SSLCert:          existFile(sslCertPath),
+			SSLKey:           existFile(sslKeyPath),
+		})
+	default:
+		manager = newLocalManager()
+	}
+	if err != nil {
+		klog.Fatalf("Exchange create %s room manager failed: %s", conf.ShareRoomType, err)
+	}
+	klog.Infof("Exchange share room type: %s", conf.ShareRoomType)
 }
 
 func Register(r *Room) {
@@ -49,3 +78,33 @@ func UnRegister(r *Room) {
 func GetRoom(roomId string) *Room {
 	return manager.Get(roomId)
 }
+
+// RegisterRoomInfo 登记开启共享的会话, 供其他用户查询加入
+func RegisterRoomInfo(info *RoomInfo) {
+	manager.AddRoomInfo(info)
+}
+
+func UnRegisterRoomInfo(roomId string) {
+	manager.DeleteRoomInfo(roomId)
+}
+
+func ListRoomInfos() []*RoomInfo {
+	return manager.ListRoomInfos()
+}
+
+func StoreSession(sid string, info []byte) {
+	manager.StoreSession(sid, info)
+}
+
+func DeleteSession(sid string) {
+	manager.DeleteSession(sid)
+}
+
+func ListSessions() [][]byte {
+	return manager.ListSessions()
+}
+
+// SendSessionTask 将任务交给会话所在的实例执行
+func SendSessionTask(sid string, task []byte) error {
+	return manager.SendSessionTask(sid, task)
+}
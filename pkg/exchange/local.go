@@ -1,12 +1,74 @@
 package exchange
 
+import "sync"
+
 func newLocalManager() *localRoomManager {
-	m := localRoomManager{newLocalCache()}
+	m := localRoomManager{
+		localCache: newLocalCache(),
+		infos:      make(map[string]*RoomInfo),
+		sessions:   make(map[string][]byte),
+	}
 	return &m
 }
 
 type localRoomManager struct {
 	*localCache
+
+	mu       sync.Mutex
+	infos    map[string]*RoomInfo
+	sessions map[string][]byte
+}
+
+func (m *localRoomManager) AddRoomInfo(info *RoomInfo) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.infos[info.Id] = info
+}
+
+func (m *localRoomManager) DeleteRoomInfo(sid string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.infos, sid)
+}
+
+func (m *localRoomManager) ListRoomInfos() []*RoomInfo {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	infos := make([]*RoomInfo, 0, len(m.infos))
+	for _, info := range m.infos {
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+func (m *localRoomManager) StoreSession(sid string, info []byte) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions[sid] = info
+}
+
+func (m *localRoomManager) DeleteSession(sid string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.sessions, sid)
+}
+
+func (m *localRoomManager) ListSessions() [][]byte {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	sessions := make([][]byte, 0, len(m.sessions))
+	for _, info := range m.sessions {
+		sessions = append(sessions, info)
+	}
+	return sessions
+}
+
+func (m *localRoomManager) SendSessionTask(sid string, task []byte) error {
+	found, err := handleSessionTask(sid, task)
+	if !found {
+		return ErrSessionNotFound
+	}
+	return err
 }
 
 func newLocalCache() *localCache {
@@ -5,6 +5,7 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/daicheng123/kubejump/pkg/utils"
 	"github.com/mediocregopher/radix/v3"
@@ -16,13 +17,22 @@ import (
 )
 
 const (
-	globalRoomsKey = "JUMPSERVER:KOKO:ROOMS"
+	globalRoomsKey = "KUBEJUMP:ROOMS"
 
-	eventsChannel = "JUMPSERVER:KOKO:EVENTS:CHANNEL"
+	globalRoomInfosKey = "KUBEJUMP:ROOM_INFOS"
 
-	resultsChannel = "JUMPSERVER:KOKO:EVENTS:RESULT"
+	globalSessionInfosKey = "KUBEJUMP:SESSION_INFOS"
 
-	sessionsChannelPrefix = "JMS:KOKO:SESSIONS:"
+	eventsChannel = "KUBEJUMP:EVENTS:CHANNEL"
+
+	resultsChannel = "KUBEJUMP:EVENTS:RESULT"
+
+	sessionsChannelPrefix = "KUBEJUMP:SESSIONS:"
+
+	// 实例心跳, 实例退出或崩溃后心跳过期, 其登记的会话视为已结束
+	instanceKeyPrefix         = "KUBEJUMP:INSTANCES:"
+	instanceHeartbeatInterval = 10 * time.Second
+	instanceHeartbeatTTL      = 30 * time.Second
 )
 
 type Config struct {
@@ -91,7 +101,7 @@ func newRedisManager(cfg Config) (*redisRoomManager, error) {
 			if err != nil {
 				return nil, err
 			}
-			klog.Errorf("Load redis SSL cert: %s, key: %s", cfg.SSLCert, cfg.SSLKey)
+			klog.Infof("Load redis SSL cert: %s, key: %s", cfg.SSLCert, cfg.SSLKey)
 			tlsCfg.Certificates = []tls.Certificate{cert}
 			tlsCfg.InsecureSkipVerify = true
 		}
@@ -198,6 +208,8 @@ func newRedisManager(cfg Config) (*redisRoomManager, error) {
 		responseChan:           make(chan chan *subscribeResponse),
 		removeRedisUserConChan: make(chan *redisChannel),
 	}
+	m.refreshInstance()
+	go m.keepAlive()
 	go m.run()
 	return m, nil
 }
@@ -260,7 +272,7 @@ func (m *redisRoomManager) storeRoomId(roomId string) {
 		klog.Errorf("Redis Cache store room %s err: %s", roomId, err)
 		return
 	}
-	klog.Errorf("Redis Cache store room %s success", roomId)
+	klog.Infof("Redis Cache store room %s success", roomId)
 }
 
 // 全局 删除room
@@ -281,6 +293,170 @@ func (m *redisRoomManager) removeRoomId(roomId string) {
 	}
 }
 
+func (m *redisRoomManager) AddRoomInfo(info *RoomInfo) {
+	body, _ := json.Marshal(info)
+	if err := m.pool.Do(radix.FlatCmd(nil, "HSET", globalRoomInfosKey, info.Id, body)); err != nil {
+		klog.Errorf("Redis cache store room %s info err: %s", info.Id, err)
+	}
+}
+
+func (m *redisRoomManager) DeleteRoomInfo(roomId string) {
+	if err := m.pool.Do(radix.Cmd(nil, "HDEL", globalRoomInfosKey, roomId)); err != nil {
+		klog.Errorf("Redis cache remove room %s info err: %s", roomId, err)
+	}
+}
+
+// ListRoomInfos 返回所有实例上开启共享的会话, 忽略已经结束的会话
+func (m *redisRoomManager) ListRoomInfos() []*RoomInfo {
+	values := make(map[string]string)
+	if err := m.pool.Do(radix.Cmd(&values, "HGETALL", globalRoomInfosKey)); err != nil {
+		klog.Errorf("Redis cache list room infos err: %s", err)
+		return nil
+	}
+	infos := make([]*RoomInfo, 0, len(values))
+	for roomId, value := range values {
+		if !m.checkRoomExist(roomId) {
+			continue
+		}
+		var info RoomInfo
+		if err := json.Unmarshal([]byte(value), &info); err != nil {
+			klog.Errorf("Redis cache unmarshal room %s info err: %s", roomId, err)
+			continue
+		}
+		infos = append(infos, &info)
+	}
+	return infos
+}
+
+func (m *redisRoomManager) keepAlive() {
+	ticker := time.NewTicker(instanceHeartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		m.refreshInstance()
+	}
+}
+
+func (m *redisRoomManager) refreshInstance() {
+	err := m.pool.Do(radix.FlatCmd(nil, "SET", instanceKeyPrefix+m.Id, time.Now().Unix(),
+		"EX", int(instanceHeartbeatTTL/time.Second)))
+	if err != nil {
+		klog.Errorf("Redis cache refresh instance %s heartbeat err: %s", m.Id, err)
+	}
+}
+
+// instanceAlive 查询失败时视为存活, 避免 redis 抖动时误删会话
+func (m *redisRoomManager) instanceAlive(instanceId string) bool {
+	var count int
+	if err := m.pool.Do(radix.Cmd(&count, "EXISTS", instanceKeyPrefix+instanceId)); err != nil {
+		klog.Errorf("Redis cache check instance %s heartbeat err: %s", instanceId, err)
+		return true
+	}
+	return count == 1
+}
+
+// sessionEntry 在线会话信息及其所在的实例
+type sessionEntry struct {
+	Instance string          `json:"instance"`
+	Session  json.RawMessage `json:"session"`
+}
+
+func (m *redisRoomManager) StoreSession(sid string, info []byte) {
+	body, _ := json.Marshal(sessionEntry{Instance: m.Id, Session: info})
+	if err := m.pool.Do(radix.FlatCmd(nil, "HSET", globalSessionInfosKey, sid, body)); err != nil {
+		klog.Errorf("Redis cache store session %s err: %s", sid, err)
+	}
+}
+
+func (m *redisRoomManager) DeleteSession(sid string) {
+	if err := m.pool.Do(radix.Cmd(nil, "HDEL", globalSessionInfosKey, sid)); err != nil {
+		klog.Errorf("Redis cache remove session %s err: %s", sid, err)
+	}
+}
+
+// ListSessions 返回所有实例上的在线会话, 同时清理心跳已过期实例上的会话
+func (m *redisRoomManager) ListSessions() [][]byte {
+	values := make(map[string]string)
+	if err := m.pool.Do(radix.Cmd(&values, "HGETALL", globalSessionInfosKey)); err != nil {
+		klog.Errorf("Redis cache list sessions err: %s", err)
+		return nil
+	}
+	alive := make(map[string]bool)
+	sessions := make([][]byte, 0, len(values))
+	stale := make([]string, 0)
+	for sid, value := range values {
+		var entry sessionEntry
+		if err := json.Unmarshal([]byte(value), &entry); err != nil || entry.Instance == "" {
+			stale = append(stale, sid)
+			continue
+		}
+		ok, checked := alive[entry.Instance]
+		if !checked {
+			ok = m.instanceAlive(entry.Instance)
+			alive[entry.Instance] = ok
+		}
+		if !ok {
+			stale = append(stale, sid)
+			continue
+		}
+		sessions = append(sessions, entry.Session)
+	}
+	if len(stale) > 0 {
+		if err := m.pool.Do(radix.Cmd(nil, "HDEL", append([]string{globalSessionInfosKey}, stale...)...)); err != nil {
+			klog.Errorf("Redis cache remove stale sessions %v err: %s", stale, err)
+		} else {
+			klog.Infof("Redis cache remove stale sessions %v", stale)
+		}
+	}
+	return sessions
+}
+
+// sessionAlive 会话已登记且所在实例心跳未过期
+func (m *redisRoomManager) sessionAlive(sid string) bool {
+	var value string
+	if err := m.pool.Do(radix.Cmd(&value, "HGET", globalSessionInfosKey, sid)); err != nil {
+		klog.Errorf("Redis cache get session %s err: %s", sid, err)
+		return true
+	}
+	var entry sessionEntry
+	if err := json.Unmarshal([]byte(value), &entry); err != nil || entry.Instance == "" {
+		return false
+	}
+	return m.instanceAlive(entry.Instance)
+}
+
+// SendSessionTask 会话不在本实例时通过事件频道转发, 由会话所在的实例执行并返回结果
+func (m *redisRoomManager) SendSessionTask(sid string, task []byte) error {
+	if found, err := handleSessionTask(sid, task); found {
+		return err
+	}
+	if !m.sessionAlive(sid) {
+		return ErrSessionNotFound
+	}
+	req := m.createRoomEventRequest(sid, TaskEvent)
+	req.Data = task
+	res, err := m.sendRequest(&req)
+	if res == nil && err != nil {
+		klog.Errorf("Redis cache send session %s task err: %s", sid, err)
+		return ErrSessionNotFound
+	}
+	return err
+}
+
+// handleRemoteTask 执行其他实例转发的任务, 会话不在本实例时不回复
+func (m *redisRoomManager) handleRemoteTask(req subscribeRequest) {
+	found, err := handleSessionTask(req.RoomId, req.Data)
+	if !found {
+		return
+	}
+	result := m.createRoomResultRequest(req.ReqId, req.RoomId, TaskResultEvent)
+	if err != nil {
+		result.Err = err.Error()
+	}
+	if err = m.publishRequest(&result); err != nil {
+		klog.Errorf("Redis cache reply request %s task event err: %s", req.ReqId, err)
+	}
+}
+
 func (m *redisRoomManager) publishCommand(channel string, p []byte) error {
 	cmd := radix.FlatCmd(nil, "PUBLISH", channel, p)
 	return m.pool.Do(cmd)
@@ -302,7 +478,7 @@ func (m *redisRoomManager) run() {
 			case JoinEvent:
 				//	校验本地 是否已经存在
 				if room := m.remoteRoomCache.Get(req.RoomId); room != nil {
-					klog.Error("Redis cache already create room %s", req.RoomId)
+					klog.Infof("Redis cache already create room %s", req.RoomId)
 					responseChan <- &subscribeResponse{
 						Req:  req,
 						room: room,
@@ -332,6 +508,15 @@ func (m *redisRoomManager) run() {
 					continue
 				}
 				responseChan <- &subscribeResponse{Req: req}
+			case TaskEvent:
+				if err := m.publishRequest(req); err != nil {
+					responseChan <- &subscribeResponse{
+						Req: req,
+						err: err,
+					}
+					continue
+				}
+				requestsMap[req.ReqId] = responseChan
 			default:
 
 			}
@@ -417,6 +602,17 @@ func (m *redisRoomManager) run() {
 					res.room = room
 					responseChan <- &res // 容量为1， 不阻塞
 					klog.Infof("Redis cache request %s finished", req.ReqId)
+				case TaskResultEvent:
+					responseChan, ok := requestsMap[req.ReqId]
+					if !ok {
+						continue
+					}
+					delete(requestsMap, req.ReqId)
+					res := &subscribeResponse{Req: &req}
+					if req.Err != "" {
+						res.err = errors.New(req.Err)
+					}
+					responseChan <- res
 				default:
 					klog.Infof("Result channel receive unhandled event %s", req.Event)
 				}
@@ -501,6 +697,12 @@ func (m *redisRoomManager) run() {
 						klog.Infof("Event channel receive room %s exit", req.RoomId)
 						m.remoteRoomCache.Delete(room)
 					}
+				case TaskEvent:
+					if _, ok := requestsMap[req.ReqId]; ok {
+						continue
+					}
+					// 任务可能阻塞, 不占用事件循环
+					go m.handleRemoteTask(req)
 				default:
 					klog.Infof("Event channel receive unhandled event %s: %v", req.Event, req)
 				}
@@ -583,6 +785,10 @@ type subscribeRequest struct {
 	RoomId  string `json:"room_id"`
 	Event   string `json:"event"`
 	Channel string `json:"-"`
+
+	// 任务事件的内容及执行结果
+	Data []byte `json:"data,omitempty"`
+	Err  string `json:"err,omitempty"`
 }
 
 func createSessionChannel(channel string) string {
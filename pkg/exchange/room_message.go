@@ -54,6 +54,10 @@ const (
 	ActionEvent = "Action"
 
 	ShareRemoveUser = "Share_REMOVE_USER"
+
+	// TaskEvent 转发给会话所在实例的终止、锁定等任务, TaskResultEvent 为执行结果
+	TaskEvent       = "Task"
+	TaskResultEvent = "TaskResult"
 )
 
 const (
@@ -65,6 +69,28 @@ type RoomManager interface {
 	Add(s *Room)
 	Delete(s *Room)
 	Get(sid string) *Room
+
+	AddRoomInfo(info *RoomInfo)
+	DeleteRoomInfo(sid string)
+	ListRoomInfos() []*RoomInfo
+
+	// 在线会话信息及任务, redis 模式下所有实例共享
+	StoreSession(sid string, info []byte)
+	DeleteSession(sid string)
+	ListSessions() [][]byte
+	SendSessionTask(sid string, task []byte) error
+}
+
+// RoomInfo 开启共享的会话信息, redis 模式下保存在 redis 中, 其他实例的用户也可以查询加入
+type RoomInfo struct {
+	Id          string            `json:"id"`
+	User        string            `json:"user"`
+	ClusterName string            `json:"cluster_name"`
+	Namespace   string            `json:"namespace"`
+	PodName     string            `json:"pod_name"`
+	Labels      map[string]string `json:"labels"`
+	ShareMode   string            `json:"share_mode"`
+	Created     string            `json:"created"`
 }
 
 var (
@@ -159,8 +185,11 @@ func (r *Room) run() {
 			}
 			switch msg.Event {
 			case DataEvent:
-				r.recentMessages.Value = msg
-				r.recentMessages = r.recentMessages.Next()
+				// 传输中的二进制数据无需回放给新加入的用户
+				if !ZMODEMStatus {
+					r.recentMessages.Value = msg
+					r.recentMessages = r.recentMessages.Next()
+				}
 			case ShareJoin:
 				key := msg.Meta.User + msg.Meta.Created
 				currentOnlineUsers[key] = msg.Meta
@@ -188,12 +217,17 @@ func (r *Room) run() {
 }
 
 func (r *Room) Subscribe(conn *Conn) {
-	r.subscriber <- conn
-
+	select {
+	case <-r.done:
+	case r.subscriber <- conn:
+	}
 }
 
 func (r *Room) UnSubscribe(conn *Conn) {
-	r.unSubscriber <- conn
+	select {
+	case <-r.done:
+	case r.unSubscriber <- conn:
+	}
 }
 
 func (r *Room) Broadcast(msg *RoomMessage) {
@@ -0,0 +1,80 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/auth"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"k8s.io/klog/v2"
+	"strconv"
+	"strings"
+)
+
+// requestAccess 发起临时访问申请并等待审批, 审批通过后直接进入目标资产
+func (h *InteractiveHandler) requestAccess(target string) {
+	term := h.term
+	reason, err := term.ReadLineWithPrompt("Reason> ")
+	if err != nil {
+		return
+	}
+	durationLine, err := term.ReadLineWithPrompt("Duration minutes (default 60)> ")
+	if err != nil {
+		return
+	}
+	var duration int
+	if durationLine = strings.TrimSpace(durationLine); durationLine != "" {
+		if duration, err = strconv.Atoi(durationLine); err != nil || duration <= 0 {
+			h.writeWarn(fmt.Sprintf("Invalid duration %s", durationLine))
+			return
+		}
+	}
+
+	req, err := h.jmsService.CreateAccessRequest(context.Background(), h.user, target, strings.TrimSpace(reason), duration)
+	if err != nil {
+		klog.Errorf("User %s create access request failed: %s", h.user.Name, err)
+		h.writeWarn(fmt.Sprintf("Create access request failed: %s", err))
+		return
+	}
+	msg := fmt.Sprintf("Access request %d for %s created. %s", req.ID, req.Target(), auth.ConfirmInstruction)
+	utils.IgnoreErrWriteString(term, utils.WrapperString(msg, utils.Green))
+	utils.IgnoreErrWriteString(term, utils.CharNewLine)
+
+	req, ok := h.waitAccessRequest(req)
+	if !ok {
+		return
+	}
+	switch req.Status {
+	case entity.AccessRequestApproved:
+		msg = fmt.Sprintf("Access request %d approved by %s", req.ID, req.Reviewer)
+		utils.IgnoreErrWriteString(term, utils.WrapperString(msg, utils.Green))
+		utils.IgnoreErrWriteString(term, utils.CharNewLine)
+		h.selectHandler.SetSelectPrepare()
+		h.selectHandler.ProxyTarget(req.ClusterName, req.Namespace, req.PodName)
+	default:
+		msg = fmt.Sprintf("Access request %d %s by %s", req.ID, req.Status, req.Reviewer)
+		if req.ReviewComment != "" {
+			msg = fmt.Sprintf("%s: %s", msg, req.ReviewComment)
+		}
+		h.writeWarn(msg)
+	}
+}
+
+// waitAccessRequest 等待审批, 连接断开或超时后撤销申请
+func (h *InteractiveHandler) waitAccessRequest(req *entity.AccessRequest) (*entity.AccessRequest, bool) {
+	latest, err := h.jmsService.WaitAccessRequest(h.sess.Sess.Context(), req.ID)
+	if err != nil {
+		if errors.Is(err, service.ErrAccessRequestTimeout) {
+			h.writeWarn(fmt.Sprintf("Access request %d was %s, canceled", req.ID, err))
+		}
+		return nil, false
+	}
+	return latest, true
+}
+
+func (h *InteractiveHandler) writeWarn(msg string) {
+	utils.IgnoreErrWriteString(h.term, utils.WrapperString(msg, utils.Red))
+	utils.IgnoreErrWriteString(h.term, utils.CharNewLine)
+}
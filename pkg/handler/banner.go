@@ -31,10 +31,15 @@ func (h *InteractiveHandler) displayBanner(sess io.ReadWriter, user string, term
 		//{id: 4, instruct: "g", helpText: "display the node that you have permission"},
 		//{id: 5, instruct: "d", helpText: "display the databases that you have permission"},
 		//{id: 6, instruct: "k", helpText: "display the kubernetes that you have permission"},
-		{id: 2, instruct: "r", helpText: "refresh kubernetes pod assets"},
+		{id: 2, instruct: "PodName/Container", helpText: "login the specified container of the pod"},
+		{id: 3, instruct: "l + ID|PodName[/Container] [-f] [-p] [--tail N] [--since 10m]", helpText: "view pod logs, press Ctrl-C to go back"},
+		{id: 4, instruct: "r", helpText: "refresh kubernetes pod assets"},
 		//{id: 8, instruct: "s", helpText: "Chinese-English-Japanese switch"},
-		{id: 3, instruct: "h", helpText: "print help"},
-		{id: 4, instruct: "q", helpText: "exit"},
+		{id: 5, instruct: "a + Cluster/Namespace[/PodName]", helpText: "request temporary access, such as: a prod/payment"},
+		{id: 6, instruct: "s + rw|ro|off", helpText: "allow others to join your next sessions"},
+		{id: 7, instruct: "j [+ ID]", helpText: "list or join shared sessions, press Ctrl-] to leave"},
+		{id: 8, instruct: "h", helpText: "print help"},
+		{id: 9, instruct: "q", helpText: "exit"},
 	}
 
 	prefix := utils.CharClear + utils.CharTab + utils.CharTab
@@ -0,0 +1,133 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/common"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"k8s.io/klog/v2"
+	"strconv"
+	"strings"
+)
+
+// searchOrProxyContainer 按 PodName/Container 搜索, pod 唯一时直接登录指定容器
+func (u *UserSelectHandler) searchOrProxyContainer(podName, container string) {
+	newPageSize := getPageSize(u.h.term, u.h.terminalConf)
+	u.currentResult = u.Retrieve(newPageSize, 0, podName)
+	u.searchKey = podName
+	var matched []*entity.Asset
+	for _, asset := range u.currentResult {
+		if asset.PodName == podName {
+			matched = append(matched, asset)
+		}
+	}
+	if len(matched) == 0 && len(u.currentResult) == 1 {
+		matched = u.currentResult
+	}
+	if len(matched) == 1 {
+		u.ProxyContainer(matched[0], container)
+		return
+	}
+	u.DisplayCurrentResult()
+}
+
+// selectContainer 确定要登录的容器, 返回空字符串时使用 API Server 的默认容器.
+// 查看日志时不要求容器处于运行状态
+func (u *UserSelectHandler) selectContainer(asset *entity.Asset, container string, requireRunning bool) (string, bool) {
+	checkStatus := func(c *entity.Container) (string, bool) {
+		if !requireRunning {
+			return c.ContainerName, true
+		}
+		return u.checkContainerStatus(c)
+	}
+	containers, err := u.h.jmsService.ListAssetContainers(context.Background(), asset)
+	if err != nil {
+		klog.Errorf("List pod %s/%s containers failed: %s", asset.Namespace, asset.PodName, err)
+		return container, true
+	}
+	if container != "" {
+		for _, c := range containers {
+			if c.ContainerName == container {
+				return checkStatus(c)
+			}
+		}
+		// 容器尚未同步时交由 API Server 校验
+		if len(containers) == 0 {
+			return container, true
+		}
+		u.h.writeWarn(fmt.Sprintf("Container %s not found in pod %s/%s", container, asset.Namespace, asset.PodName))
+		return "", false
+	}
+	switch len(containers) {
+	case 0:
+		return "", true
+	case 1:
+		return checkStatus(containers[0])
+	}
+
+	u.displayContainers(asset, containers)
+	line, err := u.h.term.ReadLineWithPrompt("[Containers]> ")
+	if err != nil {
+		return "", false
+	}
+	line = strings.TrimSpace(line)
+	if line == "" {
+		return "", false
+	}
+	if indexNum, err := strconv.Atoi(line); err == nil && indexNum > 0 && indexNum <= len(containers) {
+		return checkStatus(containers[indexNum-1])
+	}
+	for _, c := range containers {
+		if c.ContainerName == line {
+			return checkStatus(c)
+		}
+	}
+	u.h.writeWarn(fmt.Sprintf("Container %s not found in pod %s/%s", line, asset.Namespace, asset.PodName))
+	return "", false
+}
+
+func (u *UserSelectHandler) checkContainerStatus(container *entity.Container) (string, bool) {
+	if container.Status != "Running" {
+		u.h.writeWarn(fmt.Sprintf("The container %s is %s", container.ContainerName, container.Status))
+		return "", false
+	}
+	return container.ContainerName, true
+}
+
+func (u *UserSelectHandler) displayContainers(asset *entity.Asset, containers []*entity.Container) {
+	term := u.h.term
+	fields := []string{"ID", "Container", "Image", "Status", "Ready"}
+	data := make([]map[string]string, len(containers))
+	for i, c := range containers {
+		data[i] = map[string]string{
+			"ID":        strconv.Itoa(i + 1),
+			"Container": c.ContainerName,
+			"Image":     c.Image,
+			"Status":    c.Status,
+			"Ready":     strconv.FormatBool(c.Ready),
+		}
+	}
+	w, _ := term.GetSize()
+	caption := fmt.Sprintf("Pod: %s/%s, Total Count: %d", asset.Namespace, asset.PodName, len(containers))
+	table := common.WrapperTable{
+		Fields: fields,
+		Labels: fields,
+		FieldsSize: map[string][3]int{
+			"ID":        {0, 0, 5},
+			"Container": {0, 0, 0},
+			"Image":     {0, 0, 0},
+			"Status":    {0, 0, 0},
+			"Ready":     {0, 0, 6},
+		},
+		Data:        data,
+		TotalSize:   w,
+		Caption:     utils.WrapperString(caption, utils.Green),
+		TruncPolicy: common.TruncMiddle,
+	}
+	table.Initial()
+	_, _ = term.Write([]byte(table.Display()))
+	tip := "Enter ID number or container name to select, press Enter directly to go back"
+	utils.IgnoreErrWriteString(term, utils.WrapperString(tip, utils.Green))
+	utils.IgnoreErrWriteString(term, utils.CharNewLine)
+}
@@ -110,7 +110,7 @@ func NewDirectHandler(session ssh.Session, jmsService *service.JMService, optSet
 	}()
 
 	if !opts.IsTokenConnection() {
-		selectedAssets, err = jmsService.ListPodAsset(session.Context(), opts.targetAsset)
+		selectedAssets, err = jmsService.ListPodAsset(session.Context(), opts.User, opts.targetAsset)
 		if err != nil {
 			klog.Errorf("Get direct asset failed: %s", err)
 			errMsg = "Core API failed"
@@ -0,0 +1,188 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/daicheng123/kubejump/pkg/exchange"
+	"github.com/daicheng123/kubejump/pkg/proxy"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gliderlabs/ssh"
+	"k8s.io/klog/v2"
+	"net"
+	"strings"
+)
+
+const (
+	execUsageExitCode = 2
+
+	execUsage = "Usage: ssh user@jump -- [cluster/namespace/]pod[/container] -- command [args...]\n"
+)
+
+// ExecHandler 处理未申请 pty 的 ssh 会话, 在目标容器中非交互地执行命令
+type ExecHandler struct {
+	sess       ssh.Session
+	user       *entity.User
+	jmsService *service.JMService
+}
+
+func NewExecHandler(sess ssh.Session, user *entity.User, jmsService *service.JMService) *ExecHandler {
+	return &ExecHandler{
+		sess:       sess,
+		user:       user,
+		jmsService: jmsService,
+	}
+}
+
+// Run 执行命令并返回应回传给 ssh 客户端的退出码
+func (h *ExecHandler) Run() int {
+	stderr := h.sess.Stderr()
+	selector, command, ok := parseExecCommand(h.sess.Command())
+	if !ok {
+		utils.IgnoreErrWriteString(stderr, execUsage)
+		return execUsageExitCode
+	}
+	cluster, namespace, pod, container, ok := parseExecSelector(selector)
+	if !ok {
+		utils.IgnoreErrWriteString(stderr, fmt.Sprintf("Invalid pod selector %q\n", selector))
+		utils.IgnoreErrWriteString(stderr, execUsage)
+		return execUsageExitCode
+	}
+
+	ctx := h.sess.Context()
+	assets, err := h.jmsService.FindPodAssets(ctx, cluster, namespace, pod)
+	if err != nil {
+		klog.Errorf("User %s find pod %s err: %s", h.user, selector, err)
+		utils.IgnoreErrWriteString(stderr, "Core API failed\n")
+		return proxy.ExecFailedExitCode
+	}
+	switch len(assets) {
+	case 0:
+		utils.IgnoreErrWriteString(stderr, fmt.Sprintf("No pod matched %q\n", selector))
+		return proxy.ExecFailedExitCode
+	case 1:
+	default:
+		names := make([]string, 0, len(assets))
+		for _, asset := range assets {
+			names = append(names, asset.String())
+		}
+		utils.IgnoreErrWriteString(stderr, fmt.Sprintf("Pod %q is ambiguous, use cluster/namespace/pod instead: %s\n",
+			selector, strings.Join(names, ", ")))
+		return proxy.ExecFailedExitCode
+	}
+	asset := assets[0]
+	access, ok, err := h.jmsService.ValidateAssetPermission(ctx, h.user, asset)
+	if err != nil {
+		klog.Errorf("User %s validate asset %s permission err: %s", h.user, asset, err)
+		utils.IgnoreErrWriteString(stderr, "Core API failed\n")
+		return proxy.ExecFailedExitCode
+	}
+	if !ok {
+		utils.IgnoreErrWriteString(stderr, fmt.Sprintf("No permission to access %s\n", asset))
+		return proxy.ExecFailedExitCode
+	}
+
+	proxyOpts := make([]proxy.ConnectionOption, 0, 2)
+	proxyOpts = append(proxyOpts, proxy.ConnectContainer(&proxy.ContainerInfo{
+		Namespace: asset.Namespace,
+		PodName:   asset.PodName,
+		CLuster:   asset.Cluster,
+		Container: container,
+	}))
+	proxyOpts = append(proxyOpts, proxy.ConnectTokenAuthInfo(&entity.ConnectInfo{
+		User:     h.user,
+		Asset:    asset,
+		ExpireAt: access.ExpireAt,
+		ReadOnly: access.ReadOnly,
+		Upload:   access.Upload,
+		Download: access.Download,
+	}))
+	srv, err := proxy.NewProxyServer(newExecConn(h.sess), h.jmsService, proxyOpts...)
+	if err != nil {
+		klog.Errorf("create proxy server err: %s", err)
+		utils.IgnoreErrWriteString(stderr, fmt.Sprintf("Connect to %s failed: %s\n", asset, err))
+		return proxy.ExecFailedExitCode
+	}
+	return srv.Exec(command, h.sess, h.sess, stderr)
+}
+
+// parseExecCommand 将 "selector -- cmd args" 拆分为 pod 选择器和待执行命令, 前导的 "--" 会被忽略
+func parseExecCommand(args []string) (string, []string, bool) {
+	if len(args) > 0 && args[0] == "--" {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return "", nil, false
+	}
+	selector, command := args[0], args[1:]
+	if command[0] == "--" {
+		command = command[1:]
+	}
+	if selector == "" || len(command) == 0 {
+		return "", nil, false
+	}
+	return selector, command, true
+}
+
+// parseExecSelector 解析 pod 选择器, 支持 pod、pod/container、cluster/namespace/pod、cluster/namespace/pod/container
+func parseExecSelector(selector string) (cluster, namespace, pod, container string, ok bool) {
+	parts := strings.Split(selector, "/")
+	for _, part := range parts {
+		if part == "" {
+			return "", "", "", "", false
+		}
+	}
+	switch len(parts) {
+	case 1:
+		pod = parts[0]
+	case 2:
+		pod, container = parts[0], parts[1]
+	case 3:
+		cluster, namespace, pod = parts[0], parts[1], parts[2]
+	case 4:
+		cluster, namespace, pod, container = parts[0], parts[1], parts[2], parts[3]
+	default:
+		return "", "", "", "", false
+	}
+	return cluster, namespace, pod, container, true
+}
+
+var _ proxy.UserConnection = (*execConn)(nil)
+
+// execConn 非 pty 会话的用户连接, 命令的输入输出由 ProxyServer.Exec 直接读写 ssh 会话
+type execConn struct {
+	ssh.Session
+	uuid string
+}
+
+func newExecConn(sess ssh.Session) *execConn {
+	return &execConn{Session: sess, uuid: utils.UUID()}
+}
+
+func (c *execConn) ID() string {
+	return c.uuid
+}
+
+func (c *execConn) WinCh() <-chan ssh.Window {
+	return nil
+}
+
+func (c *execConn) LoginFrom() string {
+	return "ST"
+}
+
+func (c *execConn) RemoteAddr() string {
+	host, _, _ := net.SplitHostPort(c.Session.RemoteAddr().String())
+	return host
+}
+
+func (c *execConn) Pty() ssh.Pty {
+	return ssh.Pty{}
+}
+
+func (c *execConn) Context() context.Context {
+	return c.Session.Context()
+}
+
+func (c *execConn) HandleRoomEvent(string, *exchange.RoomMessage) {}
@@ -5,6 +5,7 @@ import (
 	"github.com/daicheng123/kubejump/config"
 	"github.com/daicheng123/kubejump/internal/entity"
 	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/daicheng123/kubejump/pkg/exchange"
 	"github.com/daicheng123/kubejump/pkg/terminal"
 	"github.com/gliderlabs/ssh"
 	"k8s.io/klog/v2"
@@ -26,6 +27,7 @@ func NewInteractiveHandler(sess ssh.Session, user *entity.User, jmsService *serv
 		user:       user,
 		term:       term,
 		jmsService: jmsService,
+		shareMode:  entity.ShareModeOff,
 	}
 
 	handler.Initial()
@@ -45,6 +47,9 @@ type InteractiveHandler struct {
 	selectHandler   *UserSelectHandler
 	nodes           []entity.Asset
 	assetLoadPolicy string
+
+	shareMode     string               // 之后发起的会话的共享模式
+	shareSessions []*exchange.RoomInfo // 最近一次展示的可加入会话
 }
 
 func (h *InteractiveHandler) Initial() {
@@ -98,7 +103,7 @@ func (h *InteractiveHandler) firstLoadData() {
 func (h *InteractiveHandler) loadUserPodAssets() {
 
 	pageSize := getPageSize(h.term, config.GetConf().TerminalConf)
-	resp, err := h.jmsService.ListPodsFromStorage(context.Background(), &entity.PaginationParam{
+	resp, err := h.jmsService.ListPodsFromStorage(context.Background(), h.user, &entity.PaginationParam{
 		PageSize: pageSize,
 		IsActive: true,
 		SortBy:   "cluster_ref desc",
@@ -173,6 +178,12 @@ func (h *InteractiveHandler) Dispatch() {
 			case "r":
 				klog.Infof("user %s enter %s to exit", h.user.Name, line)
 				return
+			case "s":
+				h.setShareMode("")
+				continue
+			case "j":
+				h.listShareSessions()
+				continue
 			}
 		default:
 			switch {
@@ -180,6 +191,22 @@ func (h *InteractiveHandler) Dispatch() {
 				klog.Infof("user %s enter %s to exit", h.user.Name, line)
 				return
 
+			case strings.HasPrefix(line, "a "):
+				h.requestAccess(strings.TrimSpace(line[2:]))
+				continue
+
+			case strings.HasPrefix(line, "s "):
+				h.setShareMode(strings.ToLower(strings.TrimSpace(line[2:])))
+				continue
+
+			case strings.HasPrefix(line, "j "):
+				h.joinSession(strings.TrimSpace(line[2:]))
+				continue
+
+			case strings.HasPrefix(line, "l "):
+				h.selectHandler.showLogs(strings.TrimSpace(line[2:]))
+				continue
+
 			case strings.Index(line, "/") == 0:
 				if strings.Index(line[1:], "/") == 0 {
 					line = strings.TrimSpace(line[2:])
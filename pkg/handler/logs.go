@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/kubernetes/conn"
+	"github.com/daicheng123/kubejump/pkg/proxy"
+	"k8s.io/klog/v2"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const logsUsage = "Usage: l <ID|PodName[/Container]> [-f] [-p] [--tail N] [--since 10m]"
+
+// showLogs 查看 pod 日志, 与登录容器一样受会话空闲及最大时长限制, Ctrl-C 返回 pod 列表
+func (u *UserSelectHandler) showLogs(line string) {
+	target, logOpts, err := parseLogsArgs(strings.Fields(line))
+	if err != nil {
+		u.h.writeWarn(fmt.Sprintf("%s. %s", err, logsUsage))
+		return
+	}
+	podName, container, _ := strings.Cut(target, "/")
+	asset, ok := u.findLogsTarget(podName)
+	if !ok {
+		return
+	}
+	access, ok, err := u.h.jmsService.ValidateAssetPermission(context.Background(), u.user, asset)
+	if err != nil {
+		klog.Errorf("Validate user %s asset %s permission failed: %s", u.user.Name, asset, err)
+	}
+	if !ok {
+		u.h.writeWarn(fmt.Sprintf("You don't have permission to access the pod %s/%s", asset.Namespace, asset.PodName))
+		return
+	}
+	// 查看上次退出的容器日志时容器可能并未运行
+	container, ok = u.selectContainer(asset, container, false)
+	if !ok {
+		return
+	}
+
+	proxyOpts := make([]proxy.ConnectionOption, 0, 4)
+	proxyOpts = append(proxyOpts, proxy.ConnectContainer(&proxy.ContainerInfo{
+		Namespace: asset.Namespace,
+		PodName:   asset.PodName,
+		CLuster:   asset.Cluster,
+		Container: container,
+	}))
+	// 日志会话不会修改容器, 只读授权同样可以按 Ctrl-C 退出
+	proxyOpts = append(proxyOpts, proxy.ConnectTokenAuthInfo(&entity.ConnectInfo{
+		User:     u.user,
+		Asset:    asset,
+		ExpireAt: access.ExpireAt,
+	}))
+	proxyOpts = append(proxyOpts, proxy.ConnectShareMode(u.h.shareMode))
+	proxyOpts = append(proxyOpts, proxy.ConnectLogs(&logOpts))
+	srv, err := proxy.NewProxyServer(u.h.sess, u.h.jmsService, proxyOpts...)
+	if err != nil {
+		klog.Errorf("create proxy server err: %s", err)
+		return
+	}
+	srv.Proxy()
+	u.DisplayCurrentResult()
+}
+
+// findLogsTarget 按当前列表中的 ID 或 pod 名称查找, 匹配多个时展示搜索结果
+func (u *UserSelectHandler) findLogsTarget(podName string) (*entity.Asset, bool) {
+	if indexNum, err := strconv.Atoi(podName); err == nil && len(u.currentResult) > 0 {
+		if indexNum > 0 && indexNum <= len(u.currentResult) {
+			return u.currentResult[indexNum-1], true
+		}
+	}
+	newPageSize := getPageSize(u.h.term, u.h.terminalConf)
+	u.currentResult = u.Retrieve(newPageSize, 0, podName)
+	u.searchKey = podName
+	var matched []*entity.Asset
+	for _, asset := range u.currentResult {
+		if asset.PodName == podName {
+			matched = append(matched, asset)
+		}
+	}
+	if len(matched) == 0 && len(u.currentResult) == 1 {
+		matched = u.currentResult
+	}
+	if len(matched) == 1 {
+		return matched[0], true
+	}
+	u.DisplayCurrentResult()
+	return nil, false
+}
+
+// parseLogsArgs 解析 kubectl logs 风格的参数, 未指定 --tail 时输出全部日志
+func parseLogsArgs(args []string) (string, conn.LogOptions, error) {
+	var (
+		target string
+		opts   = conn.LogOptions{TailLines: -1}
+	)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(arg, "=")
+		switch name {
+		case "-f", "--follow":
+			opts.Follow = true
+		case "-p", "--previous":
+			opts.Previous = true
+		case "--tail", "--since":
+			if !hasValue {
+				if i+1 >= len(args) {
+					return "", opts, fmt.Errorf("flag %s needs an argument", name)
+				}
+				i++
+				value = args[i]
+			}
+			if name == "--tail" {
+				lines, err := strconv.ParseInt(value, 10, 64)
+				if err != nil {
+					return "", opts, fmt.Errorf("invalid tail lines %s", value)
+				}
+				opts.TailLines = lines
+				continue
+			}
+			since, err := time.ParseDuration(value)
+			if err != nil || since <= 0 {
+				return "", opts, fmt.Errorf("invalid since duration %s", value)
+			}
+			opts.Since = since
+		default:
+			if strings.HasPrefix(arg, "-") {
+				return "", opts, fmt.Errorf("unknown flag %s", arg)
+			}
+			if target != "" {
+				return "", opts, errors.New("only one pod can be specified")
+			}
+			target = arg
+		}
+	}
+	if target == "" {
+		return "", opts, errors.New("pod is required")
+	}
+	return target, opts, nil
+}
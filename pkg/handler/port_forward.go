@@ -0,0 +1,170 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/daicheng123/kubejump/pkg/exchange"
+	"github.com/daicheng123/kubejump/pkg/proxy"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/gliderlabs/ssh"
+	gossh "golang.org/x/crypto/ssh"
+	"k8s.io/klog/v2"
+	"net"
+	"strings"
+)
+
+// localForwardChannelData direct-tcpip 通道的附加数据, 见 RFC 4254 7.2
+type localForwardChannelData struct {
+	DestAddr string
+	DestPort uint32
+
+	OriginAddr string
+	OriginPort uint32
+}
+
+// PortForwardHandler 处理 ssh -L 发起的 direct-tcpip 通道, 目标主机格式为 [cluster/namespace/]pod,
+// 例如 ssh -L 8080:prod/payment/payment-0:80
+type PortForwardHandler struct {
+	ctx        ssh.Context
+	user       *entity.User
+	jmsService *service.JMService
+}
+
+func NewPortForwardHandler(ctx ssh.Context, user *entity.User, jmsService *service.JMService) *PortForwardHandler {
+	return &PortForwardHandler{
+		ctx:        ctx,
+		user:       user,
+		jmsService: jmsService,
+	}
+}
+
+func (h *PortForwardHandler) Serve(srv *ssh.Server, newChan gossh.NewChannel) {
+	d := localForwardChannelData{}
+	if err := gossh.Unmarshal(newChan.ExtraData(), &d); err != nil {
+		_ = newChan.Reject(gossh.ConnectionFailed, "error parsing forward data: "+err.Error())
+		return
+	}
+	if srv.LocalPortForwardingCallback == nil || !srv.LocalPortForwardingCallback(h.ctx, d.DestAddr, d.DestPort) {
+		_ = newChan.Reject(gossh.Prohibited, "port forwarding is disabled")
+		return
+	}
+	cluster, namespace, pod, container, ok := parseExecSelector(d.DestAddr)
+	if !ok || container != "" || d.DestPort == 0 || d.DestPort > 65535 {
+		_ = newChan.Reject(gossh.ConnectionFailed,
+			fmt.Sprintf("invalid target %s:%d, use [cluster/namespace/]pod:port", d.DestAddr, d.DestPort))
+		return
+	}
+
+	assets, err := h.jmsService.FindPodAssets(h.ctx, cluster, namespace, pod)
+	if err != nil {
+		klog.Errorf("User %s find pod %s err: %s", h.user, d.DestAddr, err)
+		_ = newChan.Reject(gossh.ConnectionFailed, "Core API failed")
+		return
+	}
+	switch len(assets) {
+	case 0:
+		_ = newChan.Reject(gossh.ConnectionFailed, fmt.Sprintf("no pod matched %s", d.DestAddr))
+		return
+	case 1:
+	default:
+		names := make([]string, 0, len(assets))
+		for _, asset := range assets {
+			names = append(names, asset.String())
+		}
+		_ = newChan.Reject(gossh.ConnectionFailed, fmt.Sprintf("pod %s is ambiguous, use cluster/namespace/pod instead: %s",
+			d.DestAddr, strings.Join(names, ", ")))
+		return
+	}
+	asset := assets[0]
+	access, ok, err := h.jmsService.ValidateAssetPermission(h.ctx, h.user, asset)
+	if err != nil {
+		klog.Errorf("User %s validate asset %s permission err: %s", h.user, asset, err)
+		_ = newChan.Reject(gossh.ConnectionFailed, "Core API failed")
+		return
+	}
+	if !ok {
+		_ = newChan.Reject(gossh.Prohibited, fmt.Sprintf("no permission to access %s", asset))
+		return
+	}
+	// 转发到 pod 的端口可以修改服务状态, 只读授权不允许
+	if access.ReadOnly {
+		_ = newChan.Reject(gossh.Prohibited, fmt.Sprintf("read-only permission, port forwarding to %s is not allowed", asset))
+		return
+	}
+
+	tc := newTunnelConn(h.ctx)
+	proxySrv, err := proxy.NewProxyServer(tc, h.jmsService,
+		proxy.ConnectContainer(&proxy.ContainerInfo{
+			Namespace: asset.Namespace,
+			PodName:   asset.PodName,
+			CLuster:   asset.Cluster,
+		}),
+		proxy.ConnectTokenAuthInfo(&entity.ConnectInfo{
+			User:     h.user,
+			Asset:    asset,
+			ExpireAt: access.ExpireAt,
+		}))
+	if err != nil {
+		klog.Errorf("create proxy server err: %s", err)
+		_ = newChan.Reject(gossh.ConnectionFailed, err.Error())
+		return
+	}
+	err = proxySrv.Forward(int(d.DestPort), func() error {
+		ch, reqs, err := newChan.Accept()
+		if err != nil {
+			return err
+		}
+		go gossh.DiscardRequests(reqs)
+		tc.Channel = ch
+		return nil
+	})
+	if tc.Channel != nil {
+		_ = tc.Close()
+	}
+	if err != nil {
+		klog.Errorf("User %s forward to %s:%d failed: %s", h.user, asset, d.DestPort, err)
+		_ = newChan.Reject(gossh.ConnectionFailed, err.Error())
+	}
+}
+
+var _ proxy.UserConnection = (*tunnelConn)(nil)
+
+// tunnelConn 端口转发通道对应的用户连接, 通道在转发连接建立后才会被接受
+type tunnelConn struct {
+	gossh.Channel
+	ctx  ssh.Context
+	uuid string
+}
+
+func newTunnelConn(ctx ssh.Context) *tunnelConn {
+	return &tunnelConn{ctx: ctx, uuid: utils.UUID()}
+}
+
+func (c *tunnelConn) ID() string {
+	return c.uuid
+}
+
+func (c *tunnelConn) WinCh() <-chan ssh.Window {
+	return nil
+}
+
+func (c *tunnelConn) LoginFrom() string {
+	return "ST"
+}
+
+func (c *tunnelConn) RemoteAddr() string {
+	host, _, _ := net.SplitHostPort(c.ctx.RemoteAddr().String())
+	return host
+}
+
+func (c *tunnelConn) Pty() ssh.Pty {
+	return ssh.Pty{}
+}
+
+func (c *tunnelConn) Context() context.Context {
+	return c.ctx
+}
+
+func (c *tunnelConn) HandleRoomEvent(string, *exchange.RoomMessage) {}
@@ -135,7 +135,7 @@ func (u *UserSelectHandler) retrieveFromRemote(pageSize, offset int, search stri
 		SortBy:   order,
 		IsActive: true,
 	}
-	resp, err := u.h.jmsService.ListPodsFromStorage(context.Background(), reqParam)
+	resp, err := u.h.jmsService.ListPodsFromStorage(context.Background(), u.user, reqParam)
 
 	if err != nil {
 		klog.Errorf("Get user perm assets failed: %s", err.Error())
@@ -414,6 +414,11 @@ func convertAssetItemToRow(item *entity.Asset, fields map[string]string, row map
 }
 
 func (u *UserSelectHandler) SearchOrProxy(line string) {
+	// PodName/Container 直接登录指定容器
+	if podName, container, ok := strings.Cut(line, "/"); ok && podName != "" && container != "" {
+		u.searchOrProxyContainer(podName, container)
+		return
+	}
 	if indexNum, err := strconv.Atoi(line); err == nil && len(u.currentResult) > 0 {
 		if indexNum > 0 && indexNum <= len(u.currentResult) {
 			u.Proxy(u.currentResult[indexNum-1])
@@ -441,21 +446,60 @@ func (u *UserSelectHandler) SearchOrProxy(line string) {
 	u.DisplayCurrentResult()
 }
 
+// ProxyTarget 指定 pod 时直接登录, 否则展示命名空间下的 pod 列表
+func (u *UserSelectHandler) ProxyTarget(cluster, namespace, podName string) {
+	search := namespace
+	if podName != "" {
+		search = podName
+	}
+	newPageSize := getPageSize(u.h.term, u.h.terminalConf)
+	u.currentResult = u.Retrieve(newPageSize, 0, search)
+	u.searchKey = search
+	if podName != "" {
+		for _, asset := range u.currentResult {
+			if asset.ClusterName == cluster && asset.Namespace == namespace && asset.PodName == podName {
+				u.Proxy(asset)
+				return
+			}
+		}
+	}
+	u.DisplayCurrentResult()
+}
+
 func (u *UserSelectHandler) HasPrev() bool {
 	return u.hasPre
 }
 
 func (u *UserSelectHandler) Proxy(target *entity.Asset) {
+	u.ProxyContainer(target, "")
+}
+
+// ProxyContainer 登录 pod 中的容器, container 为空时多容器 pod 需用户选择
+func (u *UserSelectHandler) ProxyContainer(target *entity.Asset, container string) {
 	//targetId := target.ID
 	if target.PodStatus != "Running" {
 		msg := "The pod is inactive"
 		_, _ = u.h.term.Write([]byte(msg))
 		return
 	}
-	u.proxyAsset(target)
+	access, ok, err := u.h.jmsService.ValidateAssetPermission(context.Background(), u.user, target)
+	if err != nil {
+		klog.Errorf("Validate user %s asset %s permission failed: %s", u.user.Name, target, err)
+	}
+	if !ok {
+		msg := fmt.Sprintf("You don't have permission to access the pod %s/%s", target.Namespace, target.PodName)
+		utils.IgnoreErrWriteString(u.h.term, utils.WrapperString(msg, utils.Red))
+		utils.IgnoreErrWriteString(u.h.term, utils.CharNewLine)
+		return
+	}
+	container, ok = u.selectContainer(target, container, true)
+	if !ok {
+		return
+	}
+	u.proxyAsset(target, container, access)
 }
 
-func (u *UserSelectHandler) proxyAsset(asset *entity.Asset) {
+func (u *UserSelectHandler) proxyAsset(asset *entity.Asset, container string, access entity.AssetAccess) {
 	u.selectedPodAsset = asset
 
 	proxyOpts := make([]proxy.ConnectionOption, 0, 10)
@@ -463,14 +507,20 @@ func (u *UserSelectHandler) proxyAsset(asset *entity.Asset) {
 		Namespace: asset.Namespace,
 		PodName:   asset.PodName,
 		CLuster:   asset.Cluster,
+		Container: container,
 	}
 	proxyOpts = append(proxyOpts, proxy.ConnectContainer(containerInfo))
 
 	authInfo := &entity.ConnectInfo{
-		User:  u.user,
-		Asset: asset,
+		User:     u.user,
+		Asset:    asset,
+		ExpireAt: access.ExpireAt,
+		ReadOnly: access.ReadOnly,
+		Upload:   access.Upload,
+		Download: access.Download,
 	}
 	proxyOpts = append(proxyOpts, proxy.ConnectTokenAuthInfo(authInfo))
+	proxyOpts = append(proxyOpts, proxy.ConnectShareMode(u.h.shareMode))
 	srv, err := proxy.NewProxyServer(u.h.sess, u.h.jmsService, proxyOpts...)
 	if err != nil {
 		logger.Errorf("create proxy server err: %s", err)
@@ -0,0 +1,482 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/internal/service"
+	"github.com/daicheng123/kubejump/pkg/kubernetes/conn"
+	"github.com/gliderlabs/ssh"
+	"github.com/pkg/sftp"
+	"io"
+	"k8s.io/klog/v2"
+	"net"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// sftpContainerDepth 虚拟目录 /集群/命名空间/Pod/容器 的层级, 更深的路径映射到容器内的文件
+const sftpContainerDepth = 4
+
+// SftpHandler 将 SFTP 请求映射到虚拟目录 /cluster/namespace/pod/container/..., 容器内的文件通过 exec、tar 读写
+type SftpHandler struct {
+	sess       ssh.Session
+	user       *entity.User
+	jmsService *service.JMService
+	remoteAddr string
+
+	mux     sync.Mutex
+	targets map[string]*sftpTarget
+}
+
+func NewSftpHandler(sess ssh.Session, user *entity.User, jmsService *service.JMService) *SftpHandler {
+	host, _, _ := net.SplitHostPort(sess.RemoteAddr().String())
+	return &SftpHandler{
+		sess:       sess,
+		user:       user,
+		jmsService: jmsService,
+		remoteAddr: host,
+		targets:    make(map[string]*sftpTarget),
+	}
+}
+
+func (h *SftpHandler) Serve() {
+	handlers := sftp.Handlers{
+		FileGet:  h,
+		FilePut:  h,
+		FileCmd:  h,
+		FileList: h,
+	}
+	server := sftp.NewRequestServer(h.sess, handlers)
+	klog.Infof("User %s start sftp session from %s", h.user, h.remoteAddr)
+	if err := server.Serve(); err != nil && !errors.Is(err, io.EOF) {
+		klog.Errorf("User %s sftp session err: %s", h.user, err)
+	}
+	_ = server.Close()
+	klog.Infof("User %s sftp session closed", h.user)
+}
+
+// sftpTarget 已授权的 Pod 及其容器
+type sftpTarget struct {
+	asset      *entity.Asset
+	access     entity.AssetAccess
+	containers []string
+}
+
+// sftpPath 解析后的虚拟路径, path 为容器内的绝对路径, 仅在 depth > sftpContainerDepth 时有效
+type sftpPath struct {
+	depth     int
+	cluster   string
+	namespace string
+	pod       string
+	container string
+	path      string
+}
+
+func parseSftpPath(p string) sftpPath {
+	p = strings.Trim(path.Clean("/"+p), "/")
+	if p == "" {
+		return sftpPath{}
+	}
+	parts := strings.Split(p, "/")
+	result := sftpPath{depth: len(parts)}
+	fields := []*string{&result.cluster, &result.namespace, &result.pod, &result.container}
+	for i := 0; i < len(fields) && i < len(parts); i++ {
+		*fields[i] = parts[i]
+	}
+	if len(parts) > sftpContainerDepth {
+		result.path = "/" + strings.Join(parts[sftpContainerDepth:], "/")
+	}
+	return result
+}
+
+func (p sftpPath) podKey() string {
+	return strings.Join([]string{p.cluster, p.namespace, p.pod}, "/")
+}
+
+func (p sftpPath) inContainer() bool {
+	return p.depth > sftpContainerDepth
+}
+
+// target 查找并缓存用户有权访问的 Pod, 未授权的 Pod 与不存在的 Pod 一样返回 os.ErrNotExist
+func (h *SftpHandler) target(p sftpPath) (*sftpTarget, error) {
+	h.mux.Lock()
+	defer h.mux.Unlock()
+	if t, ok := h.targets[p.podKey()]; ok {
+		return t, nil
+	}
+	ctx := h.sess.Context()
+	assets, err := h.jmsService.FindPodAssets(ctx, p.cluster, p.namespace, p.pod)
+	if err != nil {
+		return nil, err
+	}
+	if len(assets) != 1 {
+		return nil, os.ErrNotExist
+	}
+	access, ok, err := h.jmsService.ValidateAssetPermission(ctx, h.user, assets[0])
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	containers, err := h.jmsService.ListAssetContainers(ctx, assets[0])
+	if err != nil {
+		return nil, err
+	}
+	t := &sftpTarget{asset: assets[0], access: access}
+	for _, c := range containers {
+		t.containers = append(t.containers, c.ContainerName)
+	}
+	h.targets[p.podKey()] = t
+	return t, nil
+}
+
+// containerFS 校验容器是否存在及授权是否过期, 返回操作该容器文件系统的 ContainerFS
+func (h *SftpHandler) containerFS(p sftpPath) (*sftpTarget, *conn.ContainerFS, error) {
+	t, err := h.target(p)
+	if err != nil {
+		return nil, nil, err
+	}
+	if t.access.ExpireAt.IsExpired(time.Now()) {
+		return nil, nil, os.ErrPermission
+	}
+	found := false
+	for _, c := range t.containers {
+		if c == p.container {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return nil, nil, os.ErrNotExist
+	}
+	fs := conn.NewContainerFS(
+		conn.ContainerClusterConfig(t.asset.Cluster),
+		conn.ContainerNamespace(t.asset.Namespace),
+		conn.ContainerPodName(t.asset.PodName),
+		conn.ContainerName(p.container),
+		conn.ContainerSkipTls(config.GetConf().K8sSkipTls),
+	)
+	return t, fs, nil
+}
+
+func (h *SftpHandler) Filelist(r *sftp.Request) (sftp.ListerAt, error) {
+	p := parseSftpPath(r.Filepath)
+	switch r.Method {
+	case "List":
+		files, err := h.list(p)
+		return fileInfos(files), sftpError(err)
+	case "Stat":
+		file, err := h.stat(p)
+		if err != nil {
+			return nil, sftpError(err)
+		}
+		return fileInfos{file}, nil
+	}
+	return nil, sftp.ErrSSHFxOpUnsupported
+}
+
+func (h *SftpHandler) list(p sftpPath) ([]os.FileInfo, error) {
+	if p.inContainer() || p.depth == sftpContainerDepth {
+		_, fs, err := h.containerFS(p)
+		if err != nil {
+			return nil, err
+		}
+		dir := p.path
+		if dir == "" {
+			dir = "/"
+		}
+		files, err := fs.ReadDir(h.sess.Context(), dir)
+		if err != nil {
+			return nil, err
+		}
+		showHidden := config.GetConf().SftpShowHiddenFile
+		result := make([]os.FileInfo, 0, len(files))
+		for _, f := range files {
+			if !showHidden && strings.HasPrefix(f.Name(), ".") {
+				continue
+			}
+			result = append(result, f)
+		}
+		return result, nil
+	}
+
+	var names []string
+	if p.depth == sftpContainerDepth-1 {
+		t, err := h.target(p)
+		if err != nil {
+			return nil, err
+		}
+		names = t.containers
+	} else {
+		assets, err := h.jmsService.ListUserPodAssets(h.sess.Context(), h.user, p.cluster, p.namespace)
+		if err != nil {
+			return nil, err
+		}
+		if len(assets) == 0 && p.depth > 0 {
+			return nil, os.ErrNotExist
+		}
+		seen := make(map[string]struct{})
+		for _, asset := range assets {
+			name := []string{asset.ClusterName, asset.Namespace, asset.PodName}[p.depth]
+			if _, ok := seen[name]; !ok {
+				seen[name] = struct{}{}
+				names = append(names, name)
+			}
+		}
+	}
+	sort.Strings(names)
+	result := make([]os.FileInfo, 0, len(names))
+	for _, name := range names {
+		result = append(result, virtualDir(name))
+	}
+	return result, nil
+}
+
+func (h *SftpHandler) stat(p sftpPath) (os.FileInfo, error) {
+	if p.inContainer() {
+		_, fs, err := h.containerFS(p)
+		if err != nil {
+			return nil, err
+		}
+		return fs.Stat(h.sess.Context(), p.path)
+	}
+	if p.depth == 0 {
+		return virtualDir("/"), nil
+	}
+	// 虚拟目录通过列出上级目录判断是否存在
+	names := []string{p.cluster, p.namespace, p.pod, p.container}
+	files, err := h.list(parseSftpPath(strings.Join(names[:p.depth-1], "/")))
+	if err != nil {
+		return nil, err
+	}
+	name := names[p.depth-1]
+	for _, f := range files {
+		if f.Name() == name {
+			return f, nil
+		}
+	}
+	return nil, os.ErrNotExist
+}
+
+func (h *SftpHandler) Fileread(r *sftp.Request) (io.ReaderAt, error) {
+	p := parseSftpPath(r.Filepath)
+	if !p.inContainer() {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	t, fs, err := h.containerFS(p)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	if !t.access.Download {
+		h.recordLog(p, entity.FTPOperateDownload, "", 0, os.ErrPermission)
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	// 客户端会并发乱序读取, 先下载到本地临时文件
+	file, err := os.CreateTemp("", "kubejump-sftp-")
+	if err != nil {
+		return nil, err
+	}
+	// 超过 ZipMaxSize 时中止下载
+	size, err := fs.Download(h.sess.Context(), p.path,
+		&sizeLimitWriter{w: file, max: config.GetConf().ZipMaxSizeBytes()})
+	h.recordLog(p, entity.FTPOperateDownload, "", size, err)
+	if err != nil {
+		_ = file.Close()
+		_ = os.Remove(file.Name())
+		return nil, sftpError(err)
+	}
+	return &sftpTempFile{File: file}, nil
+}
+
+func (h *SftpHandler) Filewrite(r *sftp.Request) (io.WriterAt, error) {
+	p := parseSftpPath(r.Filepath)
+	if !p.inContainer() {
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	t, fs, err := h.containerFS(p)
+	if err != nil {
+		return nil, sftpError(err)
+	}
+	if !t.access.Upload {
+		h.recordLog(p, entity.FTPOperateUpload, "", 0, os.ErrPermission)
+		return nil, sftp.ErrSSHFxPermissionDenied
+	}
+	file, err := os.CreateTemp("", "kubejump-sftp-")
+	if err != nil {
+		return nil, err
+	}
+	tmp := &sftpTempFile{File: file, maxSize: config.GetConf().ZipMaxSizeBytes()}
+	// 客户端关闭文件时再整体上传到容器
+	tmp.onClose = func(f *os.File) error {
+		if tmp.oversize.Load() {
+			h.recordLog(p, entity.FTPOperateUpload, "", 0, errSftpFileTooLarge)
+			return errSftpFileTooLarge
+		}
+		info, err := f.Stat()
+		if err != nil {
+			return err
+		}
+		err = fs.Upload(h.sess.Context(), p.path, io.NewSectionReader(f, 0, info.Size()), info.Size())
+		h.recordLog(p, entity.FTPOperateUpload, "", info.Size(), err)
+		return sftpError(err)
+	}
+	return tmp, nil
+}
+
+func (h *SftpHandler) Filecmd(r *sftp.Request) error {
+	p := parseSftpPath(r.Filepath)
+	if r.Method == "Setstat" {
+		// 不支持修改权限、属主和时间, 直接忽略以免客户端上传后报错
+		return nil
+	}
+	if !p.inContainer() {
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	t, fs, err := h.containerFS(p)
+	if err != nil {
+		return sftpError(err)
+	}
+	var (
+		operate string
+		target  string
+		run     func(ctx context.Context) error
+	)
+	switch r.Method {
+	case "Rename":
+		dst := parseSftpPath(r.Target)
+		if !dst.inContainer() || dst.podKey() != p.podKey() || dst.container != p.container {
+			return fmt.Errorf("rename across containers is not supported")
+		}
+		operate, target = entity.FTPOperateRename, dst.path
+		run = func(ctx context.Context) error { return fs.Rename(ctx, p.path, dst.path) }
+	case "Remove":
+		operate = entity.FTPOperateDelete
+		run = func(ctx context.Context) error { return fs.Remove(ctx, p.path) }
+	case "Mkdir":
+		operate = entity.FTPOperateMkdir
+		run = func(ctx context.Context) error { return fs.Mkdir(ctx, p.path) }
+	case "Rmdir":
+		operate = entity.FTPOperateRmdir
+		run = func(ctx context.Context) error { return fs.Rmdir(ctx, p.path) }
+	default:
+		return sftp.ErrSSHFxOpUnsupported
+	}
+	if !t.access.Upload {
+		h.recordLog(p, operate, target, 0, os.ErrPermission)
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	err = run(h.sess.Context())
+	h.recordLog(p, operate, target, 0, err)
+	return sftpError(err)
+}
+
+func (h *SftpHandler) recordLog(p sftpPath, operate, target string, size int64, err error) {
+	log := &entity.FTPLog{
+		UserRef:     h.user.ID,
+		User:        h.user.String(),
+		RemoteAddr:  h.remoteAddr,
+		ClusterName: p.cluster,
+		Namespace:   p.namespace,
+		PodName:     p.pod,
+		Container:   p.container,
+		Operate:     operate,
+		Path:        p.path,
+		Target:      target,
+		Filesize:    size,
+		IsSuccess:   err == nil,
+		Timestamp:   time.Now(),
+	}
+	if err != nil {
+		log.Message = err.Error()
+	}
+	klog.Infof("User %s sftp %s %s/%s:%s success: %t", h.user, operate, p.podKey(), p.container, p.path, err == nil)
+	h.jmsService.RecordFTPLog(context.Background(), log)
+}
+
+// sftpError 将包装过的文件错误转换为 sftp 状态码, sftp 库无法识别 %w 包装的 os.ErrNotExist
+func sftpError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, os.ErrNotExist):
+		return sftp.ErrSSHFxNoSuchFile
+	case errors.Is(err, os.ErrPermission):
+		return sftp.ErrSSHFxPermissionDenied
+	}
+	return err
+}
+
+func virtualDir(name string) os.FileInfo {
+	return &conn.ContainerFile{
+		FileName:    name,
+		FileMode:    os.ModeDir | 0755,
+		FileModTime: time.Now(),
+	}
+}
+
+type fileInfos []os.FileInfo
+
+func (f fileInfos) ListAt(ls []os.FileInfo, offset int64) (int, error) {
+	if offset >= int64(len(f)) {
+		return 0, io.EOF
+	}
+	n := copy(ls, f[offset:])
+	if n < len(ls) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// errSftpFileTooLarge 文件超过 ZipMaxSize 限制
+var errSftpFileTooLarge = errors.New("file size exceeds the limit")
+
+// sizeLimitWriter 累计写入超过 max 字节时返回错误, max 为 0 表示不限制
+type sizeLimitWriter struct {
+	w   io.Writer
+	max int64
+	n   int64
+}
+
+func (w *sizeLimitWriter) Write(p []byte) (int, error) {
+	if w.max > 0 && w.n+int64(len(p)) > w.max {
+		return 0, errSftpFileTooLarge
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// sftpTempFile 本地临时文件, 关闭时删除. maxSize 大于 0 时拒绝写入超出该大小的数据
+type sftpTempFile struct {
+	*os.File
+	onClose  func(f *os.File) error
+	maxSize  int64
+	oversize atomic.Bool
+}
+
+func (f *sftpTempFile) WriteAt(p []byte, off int64) (int, error) {
+	if f.maxSize > 0 && off+int64(len(p)) > f.maxSize {
+		f.oversize.Store(true)
+		return 0, errSftpFileTooLarge
+	}
+	return f.File.WriteAt(p, off)
+}
+
+func (f *sftpTempFile) Close() error {
+	var err error
+	if f.onClose != nil {
+		err = f.onClose(f.File)
+	}
+	_ = f.File.Close()
+	_ = os.Remove(f.File.Name())
+	return err
+}
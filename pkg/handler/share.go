@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/exchange"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"k8s.io/klog/v2"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+func shareEnabled() bool {
+	return config.GetConf().TerminalConf.EnableSessionShare
+}
+
+func shareModeText(mode string) string {
+	switch mode {
+	case entity.ShareModeWritable:
+		return "writable"
+	case entity.ShareModeReadOnly:
+		return "read-only"
+	default:
+		return "off"
+	}
+}
+
+// setShareMode 设置之后发起的会话是否允许其他用户加入以及加入后能否输入
+func (h *InteractiveHandler) setShareMode(mode string) {
+	if !shareEnabled() {
+		h.writeWarn("Session share is disabled")
+		return
+	}
+	switch mode {
+	case "":
+	case entity.ShareModeOff, entity.ShareModeReadOnly, entity.ShareModeWritable:
+		h.shareMode = mode
+	default:
+		h.writeWarn(fmt.Sprintf("Invalid share mode %s, expect rw, ro or off", mode))
+		return
+	}
+	msg := fmt.Sprintf("Session share mode: %s", shareModeText(h.shareMode))
+	utils.IgnoreErrWriteString(h.term, utils.WrapperString(msg, utils.Green))
+	utils.IgnoreErrWriteString(h.term, utils.CharNewLine)
+}
+
+func roomAsset(info *exchange.RoomInfo) *entity.Asset {
+	return &entity.Asset{
+		ClusterName: info.ClusterName,
+		Namespace:   info.Namespace,
+		PodName:     info.PodName,
+		Labels:      info.Labels,
+	}
+}
+
+// joinableSessions 开启共享且用户有权访问其资产的会话, 包含其他实例上的会话
+func (h *InteractiveHandler) joinableSessions() []*exchange.RoomInfo {
+	result := make([]*exchange.RoomInfo, 0)
+	for _, info := range exchange.ListRoomInfos() {
+		if info.ShareMode != entity.ShareModeReadOnly && info.ShareMode != entity.ShareModeWritable {
+			continue
+		}
+		if _, ok, err := h.jmsService.ValidateAssetPermission(context.Background(), h.user, roomAsset(info)); err != nil || !ok {
+			continue
+		}
+		result = append(result, info)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Created < result[j].Created
+	})
+	return result
+}
+
+func (h *InteractiveHandler) listShareSessions() {
+	if !shareEnabled() {
+		h.writeWarn("Session share is disabled")
+		return
+	}
+	h.shareSessions = h.joinableSessions()
+	term := h.term
+	if len(h.shareSessions) == 0 {
+		utils.IgnoreErrWriteString(term, utils.WrapperString("No shared sessions", utils.Red))
+		utils.IgnoreErrWriteString(term, utils.CharNewLine)
+		return
+	}
+	for i, info := range h.shareSessions {
+		line := fmt.Sprintf("%d\t%s\t%s\t%s/%s/%s\t%s", i+1, info.Id, info.User,
+			info.ClusterName, info.Namespace, info.PodName, shareModeText(info.ShareMode))
+		utils.IgnoreErrWriteString(term, line)
+		utils.IgnoreErrWriteString(term, utils.CharNewLine)
+	}
+	tip := "Enter j + ID number or session id to join the session"
+	utils.IgnoreErrWriteString(term, utils.WrapperString(tip, utils.Green))
+	utils.IgnoreErrWriteString(term, utils.CharNewLine)
+}
+
+func (h *InteractiveHandler) findShareSession(key string) *exchange.RoomInfo {
+	if index, err := strconv.Atoi(key); err == nil {
+		if index > 0 && index <= len(h.shareSessions) {
+			return h.shareSessions[index-1]
+		}
+		return nil
+	}
+	for _, info := range h.joinableSessions() {
+		if info.Id == key {
+			return info
+		}
+	}
+	return nil
+}
+
+// joinSession 加入其他用户的会话, 按 Ctrl-] 退出
+func (h *InteractiveHandler) joinSession(key string) {
+	if !shareEnabled() {
+		h.writeWarn("Session share is disabled")
+		return
+	}
+	info := h.findShareSession(strings.TrimSpace(key))
+	if info == nil {
+		h.writeWarn(fmt.Sprintf("Not found shared session %s", key))
+		return
+	}
+	access, ok, err := h.jmsService.ValidateAssetPermission(context.Background(), h.user, roomAsset(info))
+	if err != nil || !ok {
+		h.writeWarn(fmt.Sprintf("You don't have permission to join the session %s", info.Id))
+		return
+	}
+	room := exchange.GetRoom(info.Id)
+	if room == nil {
+		h.writeWarn(fmt.Sprintf("Session %s has ended", info.Id))
+		return
+	}
+	writable := info.ShareMode == entity.ShareModeWritable && !access.ReadOnly
+	meta := exchange.MetaMessage{
+		UserId:     h.user.Name,
+		User:       h.user.String(),
+		Created:    utils.NewNowUTCTime().String(),
+		RemoteAddr: h.sess.RemoteAddr(),
+		TerminalId: h.sess.ID(),
+		Primary:    false,
+		Writable:   writable,
+	}
+	klog.Infof("User %s join session %s of %s, writable: %v", h.user.Name, info.Id, info.User, writable)
+	mode := entity.ShareModeReadOnly
+	if writable {
+		mode = entity.ShareModeWritable
+	}
+	msg := fmt.Sprintf("Join session %s of %s (%s), press Ctrl-] to exit",
+		info.Id, info.User, shareModeText(mode))
+	utils.IgnoreErrWriteString(h.sess, utils.WrapperString(msg, utils.Green))
+	utils.IgnoreErrWriteString(h.sess, utils.CharNewLine)
+
+	conn := exchange.WrapperUserCon(h.sess)
+	room.Subscribe(conn)
+	room.Broadcast(&exchange.RoomMessage{Event: exchange.ShareJoin, Meta: meta})
+	defer func() {
+		room.Broadcast(&exchange.RoomMessage{Event: exchange.ShareLeave, Meta: meta})
+		room.UnSubscribe(conn)
+		klog.Infof("User %s leave session %s", h.user.Name, info.Id)
+	}()
+
+	readDone := make(chan struct{})
+	go func() {
+		defer close(readDone)
+		for {
+			buf := make([]byte, 1024)
+			nr, err2 := h.sess.Read(buf)
+			if nr > 0 {
+				if bytes.IndexByte(buf[:nr], utils.CharDetach) >= 0 {
+					return
+				}
+				if writable {
+					room.Receive(&exchange.RoomMessage{
+						Event: exchange.DataEvent, Body: buf[:nr], Meta: meta})
+				}
+			}
+			if err2 != nil {
+				return
+			}
+		}
+	}()
+	select {
+	case <-readDone:
+	case <-room.Done():
+	case <-h.sess.Sess.Context().Done():
+	}
+	// 关闭读取管道, 结束读取协程
+	_ = h.sess.Close()
+	<-readDone
+	utils.IgnoreErrWriteString(h.sess, utils.CharNewLine)
+	utils.IgnoreErrWriteString(h.sess, utils.WrapperString(fmt.Sprintf("Left session %s", info.Id), utils.Green))
+	utils.IgnoreErrWriteString(h.sess, utils.CharNewLine)
+}
@@ -2,6 +2,8 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/daicheng123/kubejump/pkg/exchange"
 	"github.com/daicheng123/kubejump/pkg/utils"
 	"github.com/gliderlabs/ssh"
@@ -138,5 +140,48 @@ func (w *WrapperSession) initial() {
 }
 
 func (w *WrapperSession) HandleRoomEvent(event string, msg *exchange.RoomMessage) {
-
+	switch event {
+	case exchange.ShareJoin, exchange.ShareLeave:
+		// 忽略自身加入、离开的事件
+		if msg.Meta.TerminalId == w.Uuid {
+			return
+		}
+		action := "joined"
+		if event == exchange.ShareLeave {
+			action = "left"
+		}
+		mode := "read-only"
+		if msg.Meta.Writable {
+			mode = "writable"
+		}
+		notice := fmt.Sprintf("%s %s the session (%s)", msg.Meta.User, action, mode)
+		_, _ = w.Write([]byte(utils.CharNewLine + utils.WrapperString(notice, utils.Green) + utils.CharNewLine))
+	case exchange.PauseEvent, exchange.ResumeEvent:
+		var body struct {
+			User string `json:"user"`
+		}
+		_ = json.Unmarshal(msg.Body, &body)
+		notice := fmt.Sprintf("Session is locked by admin %s, input is disabled", body.User)
+		color := utils.Red
+		if event == exchange.ResumeEvent {
+			notice = fmt.Sprintf("Session is unlocked by admin %s, input is enabled", body.User)
+			color = utils.Green
+		}
+		_, _ = w.Write([]byte(utils.CharNewLine + utils.WrapperString(notice, color) + utils.CharNewLine))
+	case exchange.ActionEvent:
+		// 传输文件的用户无需提示
+		if msg.Meta.TerminalId == w.Uuid {
+			return
+		}
+		var notice string
+		switch string(msg.Body) {
+		case exchange.ZmodemStartEvent:
+			notice = "File transfer (rz/sz) in progress"
+		case exchange.ZmodemEndEvent:
+			notice = "File transfer (rz/sz) finished"
+		default:
+			return
+		}
+		_, _ = w.Write([]byte(utils.CharNewLine + utils.WrapperString(notice, utils.Green) + utils.CharNewLine))
+	}
 }
@@ -11,6 +11,7 @@ import (
 	"io"
 	v1 "k8s.io/api/core/v1"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/remotecommand"
 	"k8s.io/klog/v2"
 	"net/http"
@@ -96,9 +97,23 @@ func ContainerPtyWin(win Windows) ContainerFunc {
 	}
 }
 
+// restConfig 按 IsSkipTls 调整 exec 使用的客户端配置, 跳过校验时不能同时携带 CA
+func (o *ContainerOptions) restConfig(client *jump_kubernetes.ClientSet) *rest.Config {
+	config := client.GetConfig()
+	if !o.IsSkipTls {
+		return config
+	}
+	config = rest.CopyConfig(config)
+	config.Insecure = true
+	config.CAData = nil
+	config.CAFile = ""
+	return config
+}
+
 type ContainerConnection struct {
-	opt   *ContainerOptions
-	shell string
+	opt    *ContainerOptions
+	shell  string
+	cancel context.CancelFunc
 
 	slaver      *slaveStream
 	winSizeChan chan *remotecommand.TerminalSize
@@ -112,7 +127,7 @@ type ContainerConnection struct {
 }
 
 func NewKubernetesConnection(options ...ContainerFunc) (*ContainerConnection, error) {
-	var opt *ContainerOptions
+	opt := &ContainerOptions{}
 	for _, setter := range options {
 		setter.apply(opt)
 	}
@@ -133,7 +148,7 @@ func NewKubernetesConnection(options ...ContainerFunc) (*ContainerConnection, er
 		return nil, err
 	}
 
-	sehll, err := FindAvailableShell(opt)
+	shell, err := FindAvailableShell(opt)
 	if err != nil {
 		return nil, err
 	}
@@ -151,9 +166,11 @@ func NewKubernetesConnection(options ...ContainerFunc) (*ContainerConnection, er
 		done:        done,
 	}
 
+	ctx, cancel := context.WithCancel(context.Background())
 	con := ContainerConnection{
 		opt:          opt,
-		shell:        sehll,
+		shell:        shell,
+		cancel:       cancel,
 		slaver:       &slaver,
 		winSizeChan:  winSizeChan,
 		done:         done,
@@ -163,7 +180,7 @@ func NewKubernetesConnection(options ...ContainerFunc) (*ContainerConnection, er
 
 	con.winSizeChan <- opt.win
 	go func() {
-		if err2 := execContainerShell(cli, &con); err2 != nil {
+		if err2 := execContainerShell(ctx, cli, &con); err2 != nil {
 			klog.Error(err2)
 		}
 		_ = con.Close()
@@ -182,7 +199,8 @@ func (c *ContainerConnection) Write(p []byte) (int, error) {
 
 func (c *ContainerConnection) Close() error {
 	c.once.Do(func() {
-		_, _ = c.stdinWriter.Write([]byte("\r\nexit\r\n"))
+		// 断开 exec 连接, 由 kubelet 回收容器中的 shell 进程
+		c.cancel()
 		_ = c.stdinWriter.Close()
 		_ = c.stdoutReader.Close()
 		close(c.done)
@@ -219,7 +237,7 @@ func (s *slaveStream) Next() *remotecommand.TerminalSize {
 	}
 }
 
-func execContainerShell(k8sClient *jump_kubernetes.ClientSet, c *ContainerConnection) error {
+func execContainerShell(ctx context.Context, k8sClient *jump_kubernetes.ClientSet, c *ContainerConnection) error {
 	req := k8sClient.K8sClientSet.CoreV1().RESTClient().Post().
 		Resource("pods").
 		Name(c.opt.PodName).
@@ -233,7 +251,7 @@ func execContainerShell(k8sClient *jump_kubernetes.ClientSet, c *ContainerConnec
 		Stderr:    true,
 		TTY:       true,
 	}, scheme.ParameterCodec)
-	exec, err := remotecommand.NewSPDYExecutor(k8sClient.GetConfig(), http.MethodPost, req.URL())
+	exec, err := remotecommand.NewSPDYExecutor(c.opt.restConfig(k8sClient), http.MethodPost, req.URL())
 	if err != nil {
 		return err
 	}
@@ -245,7 +263,7 @@ func execContainerShell(k8sClient *jump_kubernetes.ClientSet, c *ContainerConnec
 		Tty:               true,
 	}
 	// 这个 stream 是阻塞的方法
-	err = exec.StreamWithContext(context.Background(), streamOption)
+	err = exec.StreamWithContext(ctx, streamOption)
 	return err
 }
 
@@ -264,14 +282,20 @@ func (c *ContainerConnection) SetWinSize(w, h int) error {
 
 func FindAvailableShell(opt *ContainerOptions) (shell string, err error) {
 	shells := []string{"bash", "sh", "powershell", "cmd"}
+	// 首个探测失败的原因最能说明问题, 如证书校验失败或容器内没有 sh
+	var firstErr error
 	for i := range shells {
 		if err = HasShellInContainer(opt, shells[i]); err == nil {
 			return shells[i], nil
 		} else {
 			logger.Debug(err)
+			if firstErr == nil {
+				firstErr = err
+			}
 		}
 	}
-	return "", ErrNotFoundShell
+	return "", fmt.Errorf("%w in container %s (tried %s): %s",
+		ErrNotFoundShell, opt, strings.Join(shells, ", "), firstErr)
 }
 
 var scriptTmpl = `#!/bin/sh
@@ -330,7 +354,7 @@ func HasShellInContainer(opt *ContainerOptions, shell string) error {
 		Stdout:    true,
 	}, scheme.ParameterCodec)
 
-	exec, err := remotecommand.NewSPDYExecutor(client.GetConfig(), http.MethodPost, req.URL())
+	exec, err := remotecommand.NewSPDYExecutor(opt.restConfig(client), http.MethodPost, req.URL())
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,233 @@
+package conn
+
+import (
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daicheng123/kubejump/internal/entity"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/apimachinery/pkg/util/httpstream/spdy"
+	"k8s.io/apimachinery/pkg/util/remotecommand"
+)
+
+// fakeExec 模拟容器中执行的命令, 返回退出码
+type fakeExec func(command []string, stdin io.Reader, stdout io.Writer) int
+
+// newFakeExecServer 模拟 API Server 的 pods/exec 接口, 按 v4 协议建立 SPDY 流
+func newFakeExecServer(t *testing.T, run fakeExec) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasSuffix(req.URL.Path, "/exec") {
+			http.NotFound(w, req)
+			return
+		}
+		query := req.URL.Query()
+		tty := query.Get("tty") == "true"
+		expected := 1
+		for _, name := range []string{"stdin", "stdout"} {
+			if query.Get(name) == "true" {
+				expected++
+			}
+		}
+		if query.Get("stderr") == "true" && !tty {
+			expected++
+		}
+		if tty {
+			expected++
+		}
+		if _, err := httpstream.Handshake(req, w, []string{remotecommand.StreamProtocolV4Name}); err != nil {
+			return
+		}
+		streamCh := make(chan httpstream.Stream, expected)
+		conn := spdy.NewResponseUpgrader().UpgradeResponse(w, req,
+			func(stream httpstream.Stream, replySent <-chan struct{}) error {
+				streamCh <- stream
+				return nil
+			})
+		if conn == nil {
+			return
+		}
+		defer conn.Close()
+
+		streams := make(map[string]httpstream.Stream)
+		for len(streams) < expected {
+			select {
+			case stream := <-streamCh:
+				streams[stream.Headers().Get(v1.StreamType)] = stream
+			case <-time.After(5 * time.Second):
+				t.Errorf("wait exec streams timeout, got %d of %d", len(streams), expected)
+				return
+			}
+		}
+		var stdin io.Reader = strings.NewReader("")
+		if s, ok := streams[v1.StreamTypeStdin]; ok {
+			stdin = s
+		}
+		stdout := io.Discard
+		if s, ok := streams[v1.StreamTypeStdout]; ok {
+			stdout = s
+		}
+		code := run(query["command"], stdin, stdout)
+		if code != 0 {
+			status := metav1.Status{
+				Status: metav1.StatusFailure,
+				Reason: remotecommand.NonZeroExitCodeReason,
+				Details: &metav1.StatusDetails{
+					Causes: []metav1.StatusCause{{
+						Type:    remotecommand.ExitCodeCauseType,
+						Message: strconv.Itoa(code),
+					}},
+				},
+			}
+			_ = json.NewEncoder(streams[v1.StreamTypeError]).Encode(status)
+		}
+		for _, stream := range streams {
+			_ = stream.Close()
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// shellProbe 模拟 "sh -c 'command -v shell'" 的探测结果, 容器中只有 shells 中的 shell.
+// 交互式 shell 将输入原样输出
+func shellProbe(shells ...string) fakeExec {
+	return func(command []string, stdin io.Reader, stdout io.Writer) int {
+		if len(command) == 3 && command[0] == "sh" && command[1] == "-c" {
+			for _, shell := range shells {
+				if strings.HasSuffix(command[2], "command -v "+shell) {
+					_, _ = io.WriteString(stdout, "/bin/"+shell+"\n")
+					return 0
+				}
+			}
+			return 1
+		}
+		if len(command) == 1 {
+			for _, shell := range shells {
+				if command[0] == shell {
+					_, _ = io.Copy(stdout, stdin)
+					return 0
+				}
+			}
+		}
+		return 127
+	}
+}
+
+func testCluster(t *testing.T, srv *httptest.Server, withCA bool) *entity.ClusterConfig {
+	cluster := &entity.ClusterConfig{
+		ClusterName: t.Name(),
+		MasterUrl:   srv.URL,
+	}
+	if withCA {
+		cluster.CaData = string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: srv.Certificate().Raw}))
+	}
+	return cluster
+}
+
+func testContainerOptions(cluster *entity.ClusterConfig, options ...ContainerFunc) []ContainerFunc {
+	return append([]ContainerFunc{
+		ContainerClusterConfig(cluster),
+		ContainerNamespace("default"),
+		ContainerPodName("web-0"),
+		ContainerName("web"),
+	}, options...)
+}
+
+func TestFindAvailableShell(t *testing.T) {
+	tests := []struct {
+		name   string
+		shells []string
+		want   string
+	}{
+		{name: "bash", shells: []string{"bash", "sh"}, want: "bash"},
+		{name: "fallback to sh", shells: []string{"sh"}, want: "sh"},
+		{name: "no shell", shells: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeExecServer(t, shellProbe(tt.shells...))
+			opt := &ContainerOptions{}
+			for _, setter := range testContainerOptions(testCluster(t, srv, true)) {
+				setter.apply(opt)
+			}
+			shell, err := FindAvailableShell(opt)
+			if tt.want == "" {
+				if !errors.Is(err, ErrNotFoundShell) {
+					t.Fatalf("err %v, want ErrNotFoundShell", err)
+				}
+				if msg := err.Error(); !strings.Contains(msg, "tried bash, sh, powershell, cmd") ||
+					!strings.Contains(msg, opt.String()) {
+					t.Errorf("unreadable error %q", msg)
+				}
+				return
+			}
+			if err != nil || shell != tt.want {
+				t.Fatalf("shell %q err %v, want %q", shell, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewKubernetesConnection(t *testing.T) {
+	srv := newFakeExecServer(t, shellProbe("sh"))
+	conn, err := NewKubernetesConnection(testContainerOptions(testCluster(t, srv, true),
+		ContainerPtyWin(Windows{Width: 120, Height: 40}))...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	if conn.shell != "sh" {
+		t.Errorf("shell %q, want sh", conn.shell)
+	}
+	if _, err = conn.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+	buf := make([]byte, 5)
+	if _, err = io.ReadFull(conn, buf); err != nil {
+		t.Fatal(err)
+	}
+	if string(buf) != "hello" {
+		t.Errorf("read %q, want hello", buf)
+	}
+}
+
+func TestContainerSkipTls(t *testing.T) {
+	tests := []struct {
+		name    string
+		withCA  bool
+		skipTls bool
+		wantErr bool
+	}{
+		{name: "verify with ca", withCA: true},
+		{name: "unknown ca", wantErr: true},
+		{name: "skip tls verify", skipTls: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newFakeExecServer(t, shellProbe("bash"))
+			opt := &ContainerOptions{}
+			options := testContainerOptions(testCluster(t, srv, tt.withCA), ContainerSkipTls(tt.skipTls))
+			for _, setter := range options {
+				setter.apply(opt)
+			}
+			_, err := FindAvailableShell(opt)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil && !strings.Contains(err.Error(), "certificate") {
+				t.Errorf("err %v, want certificate error", err)
+			}
+		})
+	}
+}
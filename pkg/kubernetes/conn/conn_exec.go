@@ -0,0 +1,57 @@
+package conn
+
+import (
+	"context"
+	"errors"
+	jump_kubernetes "github.com/daicheng123/kubejump/pkg/kubernetes"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+	utilexec "k8s.io/client-go/util/exec"
+	"net/http"
+)
+
+// ExecCommand 在容器中以非交互方式执行命令, stdout 与 stderr 分开输出.
+// 命令正常结束时返回其退出码, 只有连接或执行失败时才返回 error
+func ExecCommand(ctx context.Context, command []string, stdin io.Reader, stdout, stderr io.Writer,
+	options ...ContainerFunc) (int, error) {
+	opt := &ContainerOptions{}
+	for _, setter := range options {
+		setter.apply(opt)
+	}
+	factory, err := jump_kubernetes.GetClientFactory()
+	if err != nil {
+		return 0, err
+	}
+	client, err := factory.GetOrCreateClient(opt.Cluster)
+	if err != nil {
+		return 0, err
+	}
+	req := client.K8sClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(opt.PodName).
+		Namespace(opt.Namespace).
+		SubResource("exec")
+	req.VersionedParams(&v1.PodExecOptions{
+		Container: opt.ContainerName,
+		Command:   command,
+		Stdin:     stdin != nil,
+		Stdout:    true,
+		Stderr:    true,
+	}, scheme.ParameterCodec)
+	exec, err := remotecommand.NewSPDYExecutor(opt.restConfig(client), http.MethodPost, req.URL())
+	if err != nil {
+		return 0, err
+	}
+	err = exec.StreamWithContext(ctx, remotecommand.StreamOptions{
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	})
+	var exitErr utilexec.ExitError
+	if errors.As(err, &exitErr) && exitErr.Exited() {
+		return exitErr.ExitStatus(), nil
+	}
+	return 0, err
+}
@@ -0,0 +1,220 @@
+package conn
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// statFormat 依次输出 大小、十六进制的原始 mode、修改时间、文件名, GNU 与 busybox 的 stat 均支持
+const statFormat = "%s %f %Y %n"
+
+const (
+	unixTypeMask = 0170000
+	unixTypeDir  = 0040000
+	unixTypeLink = 0120000
+)
+
+// ContainerFile 容器内文件的元信息, 实现 os.FileInfo
+type ContainerFile struct {
+	FileName    string
+	FileSize    int64
+	FileMode    os.FileMode
+	FileModTime time.Time
+}
+
+func (f *ContainerFile) Name() string       { return f.FileName }
+func (f *ContainerFile) Size() int64        { return f.FileSize }
+func (f *ContainerFile) Mode() os.FileMode  { return f.FileMode }
+func (f *ContainerFile) ModTime() time.Time { return f.FileModTime }
+func (f *ContainerFile) IsDir() bool        { return f.FileMode.IsDir() }
+func (f *ContainerFile) Sys() interface{}   { return nil }
+
+// ContainerFS 通过 exec 调用容器内的 stat、tar 等命令操作容器文件系统, 路径均为容器内的绝对路径
+type ContainerFS struct {
+	options []ContainerFunc
+}
+
+func NewContainerFS(options ...ContainerFunc) *ContainerFS {
+	return &ContainerFS{options: options}
+}
+
+// run 执行命令, 退出码非 0 时根据 stderr 返回 os.ErrNotExist、os.ErrPermission 等可识别的错误
+func (f *ContainerFS) run(ctx context.Context, command []string, stdin io.Reader, stdout io.Writer) error {
+	var stderr bytes.Buffer
+	code, err := ExecCommand(ctx, command, stdin, stdout, &stderr, f.options...)
+	if err != nil {
+		return err
+	}
+	if code == 0 {
+		return nil
+	}
+	msg := strings.TrimSpace(stderr.String())
+	switch {
+	case strings.Contains(msg, "No such file or directory"):
+		return fmt.Errorf("%w: %s", os.ErrNotExist, msg)
+	case strings.Contains(msg, "Permission denied"), strings.Contains(msg, "Read-only file system"):
+		return fmt.Errorf("%w: %s", os.ErrPermission, msg)
+	}
+	return fmt.Errorf("%s exit with code %d: %s", command[0], code, msg)
+}
+
+func (f *ContainerFS) Stat(ctx context.Context, name string) (*ContainerFile, error) {
+	var stdout bytes.Buffer
+	if err := f.run(ctx, []string{"stat", "-c", statFormat, name}, nil, &stdout); err != nil {
+		return nil, err
+	}
+	files := parseStatOutput(&stdout)
+	if len(files) == 0 {
+		return nil, fmt.Errorf("unexpected stat output: %q", stdout.String())
+	}
+	return files[0], nil
+}
+
+// ReadDir 列出目录下的文件, 不跟随符号链接
+func (f *ContainerFS) ReadDir(ctx context.Context, dir string) ([]*ContainerFile, error) {
+	var stdout bytes.Buffer
+	command := []string{"find", dir, "-mindepth", "1", "-maxdepth", "1", "-exec", "stat", "-c", statFormat, "{}", "+"}
+	if err := f.run(ctx, command, nil, &stdout); err != nil {
+		return nil, err
+	}
+	return parseStatOutput(&stdout), nil
+}
+
+// Download 使用 tar 打包单个文件并写入 w, 返回写入的字节数
+func (f *ContainerFS) Download(ctx context.Context, name string, w io.Writer) (int64, error) {
+	reader, writer := io.Pipe()
+	done := make(chan error, 1)
+	go func() {
+		command := []string{"tar", "cf", "-", "-C", path.Dir(name), "./" + path.Base(name)}
+		err := f.run(ctx, command, nil, writer)
+		_ = writer.CloseWithError(err)
+		done <- err
+	}()
+	n, err := copyFromTar(reader, w)
+	if err == nil {
+		// 读完 tar 包结尾的填充块, 否则 exec 会因管道关闭而失败
+		_, err = io.Copy(io.Discard, reader)
+	}
+	// 提前返回时需要关闭管道, 避免 exec 阻塞在写入
+	_ = reader.CloseWithError(err)
+	execErr := <-done
+	switch {
+	case err == nil:
+		err = execErr
+	case errors.Is(err, io.EOF):
+		// 文件不存在时 tar 仍会输出一个空的 tar 包
+		if err = execErr; err == nil {
+			err = fmt.Errorf("%w: %s", os.ErrNotExist, name)
+		}
+	}
+	return n, err
+}
+
+func copyFromTar(r io.Reader, w io.Writer) (int64, error) {
+	tr := tar.NewReader(r)
+	header, err := tr.Next()
+	if err != nil {
+		return 0, err
+	}
+	if header.Typeflag != tar.TypeReg {
+		return 0, fmt.Errorf("%s is not a regular file", strings.Trim(strings.TrimPrefix(header.Name, "./"), "/"))
+	}
+	return io.Copy(w, tr)
+}
+
+// Upload 将 r 中的 size 个字节以 tar 包的形式解压到容器内的 name
+func (f *ContainerFS) Upload(ctx context.Context, name string, r io.Reader, size int64) error {
+	reader, writer := io.Pipe()
+	go func() {
+		tw := tar.NewWriter(writer)
+		err := tw.WriteHeader(&tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     path.Base(name),
+			Mode:     0644,
+			Size:     size,
+			ModTime:  time.Now(),
+		})
+		if err == nil {
+			_, err = io.CopyN(tw, r, size)
+		}
+		if err == nil {
+			err = tw.Close()
+		}
+		_ = writer.CloseWithError(err)
+	}()
+	err := f.run(ctx, []string{"tar", "xf", "-", "-C", path.Dir(name)}, reader, io.Discard)
+	_ = reader.Close()
+	return err
+}
+
+func (f *ContainerFS) Rename(ctx context.Context, oldName, newName string) error {
+	return f.run(ctx, []string{"mv", "--", oldName, newName}, nil, io.Discard)
+}
+
+func (f *ContainerFS) Remove(ctx context.Context, name string) error {
+	return f.run(ctx, []string{"rm", "--", name}, nil, io.Discard)
+}
+
+func (f *ContainerFS) Mkdir(ctx context.Context, name string) error {
+	return f.run(ctx, []string{"mkdir", "--", name}, nil, io.Discard)
+}
+
+func (f *ContainerFS) Rmdir(ctx context.Context, name string) error {
+	return f.run(ctx, []string{"rmdir", "--", name}, nil, io.Discard)
+}
+
+// parseStatOutput 解析 statFormat 格式的输出, 忽略无法解析的行
+func parseStatOutput(r io.Reader) []*ContainerFile {
+	files := make([]*ContainerFile, 0)
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		file, err := parseStatLine(scanner.Text())
+		if err != nil {
+			continue
+		}
+		files = append(files, file)
+	}
+	return files
+}
+
+func parseStatLine(line string) (*ContainerFile, error) {
+	fields := strings.SplitN(line, " ", 4)
+	if len(fields) != 4 {
+		return nil, errors.New("invalid stat line")
+	}
+	size, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	rawMode, err := strconv.ParseUint(fields[1], 16, 32)
+	if err != nil {
+		return nil, err
+	}
+	modTime, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	mode := os.FileMode(rawMode & 0777)
+	switch rawMode & unixTypeMask {
+	case unixTypeDir:
+		mode |= os.ModeDir
+	case unixTypeLink:
+		mode |= os.ModeSymlink
+	}
+	return &ContainerFile{
+		FileName:    path.Base(fields[3]),
+		FileSize:    size,
+		FileMode:    mode,
+		FileModTime: time.Unix(modTime, 0),
+	}, nil
+}
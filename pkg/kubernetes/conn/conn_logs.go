@@ -0,0 +1,126 @@
+package conn
+
+import (
+	"bytes"
+	"context"
+	jump_kubernetes "github.com/daicheng123/kubejump/pkg/kubernetes"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/klog/v2"
+	"sync"
+	"time"
+)
+
+const charCtrlC = '\x03'
+
+// LogOptions 与 kubectl logs 的参数对应, TailLines 小于 0 时输出全部日志
+type LogOptions struct {
+	Follow    bool
+	TailLines int64
+	Since     time.Duration
+	Previous  bool
+}
+
+// LogsConnection 将容器日志作为会话的服务端输出, 用户按 Ctrl-C 时结束
+type LogsConnection struct {
+	opt    *ContainerOptions
+	cancel context.CancelFunc
+	stream io.ReadCloser
+
+	// 换行转换为 \r\n 后未能放入读取缓冲区的部分
+	pending []byte
+
+	once sync.Once
+}
+
+func NewLogsConnection(logOpts LogOptions, options ...ContainerFunc) (*LogsConnection, error) {
+	opt := &ContainerOptions{}
+	for _, setter := range options {
+		setter.apply(opt)
+	}
+	factory, err := jump_kubernetes.GetClientFactory()
+	if err != nil {
+		return nil, err
+	}
+	client, err := factory.GetOrCreateClient(opt.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	coreClient, err := opt.coreClient(client)
+	if err != nil {
+		return nil, err
+	}
+	podLogOpts := &v1.PodLogOptions{
+		Container: opt.ContainerName,
+		Follow:    logOpts.Follow,
+		Previous:  logOpts.Previous,
+	}
+	if logOpts.TailLines >= 0 {
+		podLogOpts.TailLines = &logOpts.TailLines
+	}
+	if logOpts.Since > 0 {
+		seconds := int64(logOpts.Since.Seconds())
+		podLogOpts.SinceSeconds = &seconds
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := coreClient.Pods(opt.Namespace).GetLogs(opt.PodName, podLogOpts).Stream(ctx)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &LogsConnection{
+		opt:    opt,
+		cancel: cancel,
+		stream: stream,
+	}, nil
+}
+
+// coreClient 跳过证书校验时需要使用单独的客户端
+func (o *ContainerOptions) coreClient(client *jump_kubernetes.ClientSet) (corev1client.CoreV1Interface, error) {
+	if !o.IsSkipTls {
+		return client.K8sClientSet.CoreV1(), nil
+	}
+	return corev1client.NewForConfig(o.restConfig(client))
+}
+
+// Read 用户终端处于 raw 模式, 需要将日志中的 \n 转换为 \r\n
+func (c *LogsConnection) Read(p []byte) (int, error) {
+	if len(c.pending) == 0 {
+		buf := make([]byte, len(p))
+		n, err := c.stream.Read(buf)
+		if n == 0 {
+			return 0, err
+		}
+		c.pending = bytes.ReplaceAll(buf[:n], []byte("\n"), []byte("\r\n"))
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// Write 日志会话不接收输入, Ctrl-C 结束会话
+func (c *LogsConnection) Write(p []byte) (int, error) {
+	if bytes.IndexByte(p, charCtrlC) >= 0 {
+		_ = c.Close()
+	}
+	return len(p), nil
+}
+
+func (c *LogsConnection) SetWinSize(width, height int) error {
+	return nil
+}
+
+func (c *LogsConnection) KeepAlive() error {
+	return nil
+}
+
+func (c *LogsConnection) Close() error {
+	c.once.Do(func() {
+		c.cancel()
+		_ = c.stream.Close()
+		klog.Infof("K8s %s logs connection close", c.opt.String())
+	})
+	return nil
+}
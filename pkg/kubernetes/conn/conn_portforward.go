@@ -0,0 +1,126 @@
+package conn
+
+import (
+	"context"
+	"fmt"
+	jump_kubernetes "github.com/daicheng123/kubejump/pkg/kubernetes"
+	"io"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/httpstream"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+	"k8s.io/klog/v2"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// PortForwardConnection 通过 API Server 转发到 pod 端口的单个连接, 与 kubectl port-forward 处理一个本地连接相同
+type PortForwardConnection struct {
+	opt  *ContainerOptions
+	port int
+
+	streamConn  httpstream.Connection
+	errorStream httpstream.Stream
+	dataStream  httpstream.Stream
+
+	once sync.Once
+}
+
+// NewPortForwardConnection 建立到 pod 端口的转发流, pod 不存在或没有权限时返回错误
+func NewPortForwardConnection(port int, options ...ContainerFunc) (*PortForwardConnection, error) {
+	opt := &ContainerOptions{}
+	for _, setter := range options {
+		setter.apply(opt)
+	}
+	factory, err := jump_kubernetes.GetClientFactory()
+	if err != nil {
+		return nil, err
+	}
+	client, err := factory.GetOrCreateClient(opt.Cluster)
+	if err != nil {
+		return nil, err
+	}
+	req := client.K8sClientSet.CoreV1().RESTClient().Post().
+		Resource("pods").
+		Name(opt.PodName).
+		Namespace(opt.Namespace).
+		SubResource("portforward")
+	transport, upgrader, err := spdy.RoundTripperFor(opt.restConfig(client))
+	if err != nil {
+		return nil, err
+	}
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, req.URL())
+	streamConn, _, err := dialer.Dial(portforward.PortForwardProtocolV1Name)
+	if err != nil {
+		return nil, err
+	}
+	c := &PortForwardConnection{opt: opt, port: port, streamConn: streamConn}
+
+	headers := http.Header{}
+	headers.Set(v1.StreamType, v1.StreamTypeError)
+	headers.Set(v1.PortHeader, strconv.Itoa(port))
+	headers.Set(v1.PortForwardRequestIDHeader, "0")
+	if c.errorStream, err = streamConn.CreateStream(headers); err != nil {
+		_ = streamConn.Close()
+		return nil, err
+	}
+	// 错误流只读
+	_ = c.errorStream.Close()
+	headers.Set(v1.StreamType, v1.StreamTypeData)
+	if c.dataStream, err = streamConn.CreateStream(headers); err != nil {
+		_ = streamConn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Forward 在 stream 与 pod 端口之间双向复制数据, 直至任一方结束或 ctx 取消
+func (c *PortForwardConnection) Forward(ctx context.Context, stream io.ReadWriter) error {
+	errorChan := make(chan error, 1)
+	go func() {
+		message, err := io.ReadAll(c.errorStream)
+		switch {
+		case err != nil:
+			errorChan <- fmt.Errorf("read error stream of port %d: %w", c.port, err)
+		case len(message) > 0:
+			errorChan <- fmt.Errorf("forward port %d: %s", c.port, message)
+		}
+		close(errorChan)
+	}()
+
+	localDone := make(chan struct{})
+	remoteDone := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(stream, c.dataStream)
+		close(remoteDone)
+	}()
+	go func() {
+		// 用户不再发送数据时通知 pod 一侧
+		defer c.dataStream.Close()
+		if _, err := io.Copy(c.dataStream, stream); err != nil {
+			close(localDone)
+		}
+	}()
+
+	select {
+	case <-remoteDone:
+	case <-localDone:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-errorChan:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *PortForwardConnection) Close() error {
+	c.once.Do(func() {
+		_ = c.streamConn.Close()
+		klog.Infof("K8s %s port %d forward connection close", c.opt.String(), c.port)
+	})
+	return nil
+}
@@ -0,0 +1,158 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/kubernetes/conn"
+	"github.com/daicheng123/kubejump/pkg/session"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"io"
+	"k8s.io/klog/v2"
+	"strings"
+	"time"
+)
+
+// ExecFailedExitCode 命令未能在容器中执行 (被拒绝、连接失败等) 时返回的退出码
+const ExecFailedExitCode = 255
+
+// Exec 在目标容器中以非交互方式执行命令, 与交互会话一样经过命令过滤并记录会话和命令审计.
+// 返回值为远端命令的退出码
+func (s *ProxyServer) Exec(command []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	// 命令执行期间同样受会话最长时间和授权截止时间限制
+	deadline, deadlineReason := s.execDeadline(time.Now())
+	ctx, cancel := context.WithDeadline(s.UserConn.Context(), deadline)
+	defer cancel()
+	s.sessionInfo.Type = entity.COMMANDType
+	input := strings.Join(command, " ")
+	cmd := &entity.Command{Input: input, Timestamp: time.Now()}
+
+	if s.connOpts.authInfo.ReadOnly {
+		writeExecError(stderr, "Read-only session, exec is not allowed")
+		return ExecFailedExitCode
+	}
+	acls, err := s.jmsService.ListSessionCommandFilters(ctx, s.connOpts.authInfo.User, s.connOpts.authInfo.Asset)
+	if err != nil {
+		klog.Errorf("Session[%s] load command filters failed: %s", s.ID, err)
+		writeExecError(stderr, "Load command filters failed, exec is not allowed")
+		cmd.FilterAction = entity.CommandFilterDeny
+		s.recordCommand(cmd)
+		return ExecFailedExitCode
+	}
+	action, ok := s.checkExecCommand(ctx, acls, input, stderr)
+	cmd.FilterAction = action
+	if !ok {
+		s.recordCommand(cmd)
+		return ExecFailedExitCode
+	}
+	// 标准输入中的命令无法逐条过滤, 存在拒绝或复核规则时不转发标准输入
+	if acls.Restricted() {
+		stdin = nil
+	}
+
+	var terminatedBy string
+	traceSession := session.NewSession(s.sessionInfo, func(task *entity.TerminalTask) error {
+		if task.Name != entity.TaskKillSession {
+			return fmt.Errorf("exec session unsupported task %s", task.Name)
+		}
+		terminatedBy = task.Kwargs.TerminatedBy
+		cancel()
+		klog.Infof("Session[%s] receive terminate task from %s", s.ID, terminatedBy)
+		return nil
+	})
+	session.AddSession(traceSession)
+	defer session.RemoveSession(traceSession)
+	s.jmsService.CreateSession(context.Background(), s.sessionInfo)
+	klog.Infof("Conn[%s] exec `%s` in %s", s.UserConn.ID(), input, s.connOpts.authInfo.Asset)
+
+	output := &excerptWriter{max: commandOutputMaxSize}
+	code, err := conn.ExecCommand(ctx, command, stdin, io.MultiWriter(stdout, output), stderr,
+		s.containerOptions(s.connOpts.authInfo.Asset.Cluster)...)
+	timeout := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	switch {
+	case timeout && deadlineReason == entity.ExitReasonExpired:
+		writeExecError(stderr, "Permission has expired, disconnect")
+		code = ExecFailedExitCode
+	case timeout:
+		writeExecError(stderr, "Session max time reached, disconnect")
+		code = ExecFailedExitCode
+	case err != nil:
+		klog.Errorf("Session[%s] exec `%s` failed: %s", s.ID, input, err)
+		writeExecError(stderr, fmt.Sprintf("Exec failed: %s", err))
+		code = ExecFailedExitCode
+	}
+	cmd.Output = output.String()
+	s.recordCommand(cmd)
+
+	dateEnd := time.Now()
+	s.sessionInfo.DateEnd = &dateEnd
+	s.sessionInfo.ExitReason = entity.ExitReasonNormal
+	switch {
+	case terminatedBy != "":
+		s.sessionInfo.ExitReason = entity.ExitReasonAdminTerminate
+		s.sessionInfo.TerminatedBy = terminatedBy
+	case timeout:
+		s.sessionInfo.ExitReason = deadlineReason
+	}
+	s.jmsService.FinishSession(context.Background(), s.sessionInfo)
+	return code
+}
+
+// execDeadline 返回会话最长时间与授权截止时间中较早的一个, 以及到达时的结束原因
+func (s *ProxyServer) execDeadline(now time.Time) (time.Time, string) {
+	deadline := now.Add(time.Duration(s.terminalConf.MaxSessionTime) * time.Hour)
+	expireAt := s.connOpts.authInfo.ExpireAt
+	if expireAt != entity.ExpireNever && time.Unix(int64(expireAt), 0).Before(deadline) {
+		return time.Unix(int64(expireAt), 0), entity.ExitReasonExpired
+	}
+	return deadline, entity.ExitReasonMaxTime
+}
+
+// checkExecCommand 按命令过滤规则检查命令, 返回命中规则的动作以及是否允许执行
+func (s *ProxyServer) checkExecCommand(ctx context.Context, acls entity.CommandFilterACLs, command string,
+	stderr io.Writer) (string, bool) {
+	acl := acls.Match(command)
+	if acl == nil {
+		return "", true
+	}
+	switch acl.Action {
+	case entity.CommandFilterDeny:
+		writeExecError(stderr, fmt.Sprintf("Command `%s` is forbidden by rule %s", command, acl.Name))
+		return acl.Action, false
+	case entity.CommandFilterWarn:
+		writeExecError(stderr, fmt.Sprintf("Warning: command `%s` matched rule %s and has been recorded", command, acl.Name))
+	case entity.CommandFilterReview:
+		msg := fmt.Sprintf("Command `%s` requires approval by rule %s, waiting for review", command, acl.Name)
+		writeExecError(stderr, msg)
+		approved, msg := s.reviewCommand(ctx, command, acl)
+		writeExecError(stderr, msg)
+		return acl.Action, approved
+	}
+	return acl.Action, true
+}
+
+func writeExecError(w io.Writer, msg string) {
+	utils.IgnoreErrWriteString(w, msg+"\n")
+}
+
+// excerptWriter 只保留输出的前 max 个字节, 用于命令审计
+type excerptWriter struct {
+	max int
+	buf strings.Builder
+}
+
+func (w *excerptWriter) Write(p []byte) (int, error) {
+	if remain := w.max - w.buf.Len(); remain > 0 {
+		if len(p) > remain {
+			w.buf.Write(p[:remain])
+		} else {
+			w.buf.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+func (w *excerptWriter) String() string {
+	return w.buf.String()
+}
@@ -0,0 +1,221 @@
+package proxy
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	stateNormal = iota
+	stateEsc
+	stateEscSkip
+	stateCSI
+	stateOSC
+	stateOSCEsc
+)
+
+// lineEmulator 单行终端模拟器, 根据服务端回显还原光标所在行的内容,
+// 退格、方向键、Tab 补全等编辑操作都会体现在回显的控制序列中
+type lineEmulator struct {
+	line   []rune
+	cursor int
+
+	state     int
+	csiParams []byte
+
+	// altScreen 处于 vim、top 等全屏程序的备用屏幕
+	altScreen bool
+
+	remain []byte
+
+	onLineFeed func(line string)
+}
+
+func (e *lineEmulator) Line() string {
+	return string(e.line)
+}
+
+func (e *lineEmulator) Reset() {
+	e.line = e.line[:0]
+	e.cursor = 0
+}
+
+func (e *lineEmulator) Feed(p []byte) {
+	if len(e.remain) > 0 {
+		p = append(e.remain, p...)
+		e.remain = nil
+	}
+	for len(p) > 0 {
+		r, size := utf8.DecodeRune(p)
+		if r == utf8.RuneError && size == 1 && !utf8.FullRune(p) {
+			e.remain = append(e.remain, p...)
+			return
+		}
+		p = p[size:]
+		e.feedRune(r)
+	}
+}
+
+func (e *lineEmulator) feedRune(r rune) {
+	switch e.state {
+	case stateEsc:
+		switch r {
+		case '[':
+			e.state = stateCSI
+			e.csiParams = e.csiParams[:0]
+		case ']':
+			e.state = stateOSC
+		case '(', ')', '*', '+', '#':
+			e.state = stateEscSkip
+		default:
+			e.state = stateNormal
+		}
+		return
+	case stateEscSkip:
+		e.state = stateNormal
+		return
+	case stateCSI:
+		switch {
+		case r >= 0x30 && r <= 0x3f:
+			e.csiParams = append(e.csiParams, byte(r))
+		case r >= 0x20 && r <= 0x2f:
+		case r >= 0x40 && r <= 0x7e:
+			e.state = stateNormal
+			e.handleCSI(r)
+		default:
+			e.state = stateNormal
+		}
+		return
+	case stateOSC:
+		switch r {
+		case '\a':
+			e.state = stateNormal
+		case 0x1b:
+			e.state = stateOSCEsc
+		}
+		return
+	case stateOSCEsc:
+		e.state = stateNormal
+		return
+	}
+
+	switch r {
+	case 0x1b:
+		e.state = stateEsc
+	case '\r':
+		e.cursor = 0
+	case '\n':
+		line := string(e.line)
+		e.Reset()
+		if e.onLineFeed != nil {
+			e.onLineFeed(line)
+		}
+	case '\b':
+		if e.cursor > 0 {
+			e.cursor--
+		}
+	default:
+		if r < 0x20 || r == 0x7f {
+			return
+		}
+		e.write(r)
+	}
+}
+
+func (e *lineEmulator) write(r rune) {
+	for len(e.line) < e.cursor {
+		e.line = append(e.line, ' ')
+	}
+	if e.cursor < len(e.line) {
+		e.line[e.cursor] = r
+	} else {
+		e.line = append(e.line, r)
+	}
+	e.cursor++
+}
+
+func (e *lineEmulator) handleCSI(final rune) {
+	params := string(e.csiParams)
+	if strings.HasPrefix(params, "?") {
+		switch final {
+		case 'h', 'l':
+			for _, mode := range strings.Split(params[1:], ";") {
+				switch mode {
+				case "47", "1047", "1049":
+					e.altScreen = final == 'h'
+				}
+			}
+		}
+		return
+	}
+	n := csiParam(params, 0, 1)
+	switch final {
+	case 'D':
+		e.cursor -= n
+		if e.cursor < 0 {
+			e.cursor = 0
+		}
+	case 'C':
+		e.cursor += n
+	case 'G':
+		e.cursor = n - 1
+	case 'H', 'f':
+		e.cursor = csiParam(params, 1, 1) - 1
+	case 'K':
+		switch csiParam(params, 0, 0) {
+		case 0:
+			if e.cursor < len(e.line) {
+				e.line = e.line[:e.cursor]
+			}
+		case 1:
+			for i := 0; i <= e.cursor && i < len(e.line); i++ {
+				e.line[i] = ' '
+			}
+		case 2:
+			e.line = e.line[:0]
+		}
+	case 'J':
+		if csiParam(params, 0, 0) >= 2 {
+			e.line = e.line[:0]
+		} else if e.cursor < len(e.line) {
+			e.line = e.line[:e.cursor]
+		}
+	case 'P':
+		if e.cursor < len(e.line) {
+			end := e.cursor + n
+			if end > len(e.line) {
+				end = len(e.line)
+			}
+			e.line = append(e.line[:e.cursor], e.line[end:]...)
+		}
+	case '@':
+		if e.cursor < len(e.line) {
+			blank := make([]rune, n)
+			for i := range blank {
+				blank[i] = ' '
+			}
+			e.line = append(e.line[:e.cursor], append(blank, e.line[e.cursor:]...)...)
+		}
+	case 'X':
+		for i := e.cursor; i < e.cursor+n && i < len(e.line); i++ {
+			e.line[i] = ' '
+		}
+	}
+	if e.cursor < 0 {
+		e.cursor = 0
+	}
+}
+
+// csiParam 取第 index 个参数, 缺省或为 0 时返回默认值
+func csiParam(params string, index, defaultValue int) int {
+	fields := strings.Split(params, ";")
+	if index >= len(fields) {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(fields[index])
+	if err != nil || (n == 0 && defaultValue > 0) {
+		return defaultValue
+	}
+	return n
+}
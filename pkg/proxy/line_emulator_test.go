@@ -0,0 +1,107 @@
+package proxy
+
+import (
+	"reflect"
+	"testing"
+)
+
+// 以下输出均取自 bash readline 在 xterm 下的回显
+func TestLineEmulator(t *testing.T) {
+	tests := []struct {
+		name      string
+		feeds     []string
+		wantLine  string
+		wantLines []string
+		altScreen bool
+	}{
+		{
+			name:     "plain typing",
+			feeds:    []string{"$ ", "l", "s", " -l"},
+			wantLine: "$ ls -l",
+		},
+		{
+			name:     "backspace",
+			feeds:    []string{"$ lss", "\b\x1b[K"},
+			wantLine: "$ ls",
+		},
+		{
+			name:     "backspace in the middle",
+			feeds:    []string{"$ cat /tmpp/a", "\b\b\b", "\bp/a\x1b[K\b\b\b"},
+			wantLine: "$ cat /tmp/a",
+		},
+		{
+			name:     "left arrow and insert by rewriting the tail",
+			feeds:    []string{"$ ls /tmp", "\b\b\b\b", "-a /tmp\b\b\b\b"},
+			wantLine: "$ ls -a /tmp",
+		},
+		{
+			name:     "left arrow and insert by ICH",
+			feeds:    []string{"$ ls /tmp", "\x1b[4D", "\x1b[1@-\x1b[1@a\x1b[1@ "},
+			wantLine: "$ ls -a /tmp",
+		},
+		{
+			name:     "delete under cursor",
+			feeds:    []string{"$ rm -rf /", "\x1b[4D", "\x1b[P\x1b[P"},
+			wantLine: "$ rm - /",
+		},
+		{
+			name:     "tab completion",
+			feeds:    []string{"$ cat /etc/hostn", "ame "},
+			wantLine: "$ cat /etc/hostname ",
+		},
+		{
+			name:     "history up replaces the line",
+			feeds:    []string{"$ ls", "\r\x1b[C\x1b[C\x1b[Kkubectl get pods"},
+			wantLine: "$ kubectl get pods",
+		},
+		{
+			name:     "title sequence in prompt",
+			feeds:    []string{"\x1b]0;root@pod: ~\a\x1b[01;32mroot@pod\x1b[00m:~# ", "id"},
+			wantLine: "root@pod:~# id",
+		},
+		{
+			name:     "utf8 split across reads",
+			feeds:    []string{"$ echo \xe4\xb8", "\xad\xe6\x96\x87"},
+			wantLine: "$ echo 中文",
+		},
+		{
+			name:      "command output lines",
+			feeds:     []string{"$ ls\r\n", "a.txt\r\nb.txt\r\n$ "},
+			wantLine:  "$ ",
+			wantLines: []string{"$ ls", "a.txt", "b.txt"},
+		},
+		{
+			name:      "enter alternate screen",
+			feeds:     []string{"$ vim\r\n", "\x1b[?1049h\x1b[22;0;0t\x1b[H\x1b[2J~"},
+			wantLine:  "~",
+			wantLines: []string{"$ vim"},
+			altScreen: true,
+		},
+		{
+			name:      "leave alternate screen",
+			feeds:     []string{"\x1b[?1049h~", "\x1b[?1049l\r$ "},
+			wantLine:  "$ ",
+			altScreen: false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var lines []string
+			e := lineEmulator{onLineFeed: func(line string) {
+				lines = append(lines, line)
+			}}
+			for _, feed := range tt.feeds {
+				e.Feed([]byte(feed))
+			}
+			if got := e.Line(); got != tt.wantLine {
+				t.Errorf("line = %q, want %q", got, tt.wantLine)
+			}
+			if !reflect.DeepEqual(lines, tt.wantLines) {
+				t.Errorf("lines = %q, want %q", lines, tt.wantLines)
+			}
+			if e.altScreen != tt.altScreen {
+				t.Errorf("altScreen = %t, want %t", e.altScreen, tt.altScreen)
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/exchange"
+	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/daicheng123/kubejump/pkg/zmodem"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 单条命令记录的输出摘要上限
+const commandOutputMaxSize = 1024
+
+const (
+	// echoSettleDelay 服务端输出停顿超过该时长视为回显完成
+	echoSettleDelay = 20 * time.Millisecond
+	// echoWaitTimeout 等待回显的最长时间, 超时后不再等待
+	echoWaitTimeout = 2 * time.Second
+
+	// zmodemHandshakeTimeout 服务端发出 ZMODEM 初始帧后等待用户终端回复的时间
+	zmodemHandshakeTimeout = 5 * time.Second
+	// zmodemFrameOverhead 统计转发字节数时为帧头、CRC 预留的开销
+	zmodemFrameOverhead = 4096
+)
+
+const charCtrlC = '\x03'
+
+// noEchoPromptPattern sudo、su、mysql -p 等提示输入密码时关闭回显, 提示以冒号结尾或包含 password
+var noEchoPromptPattern = regexp.MustCompile(`(?i)password|passphrase|密码|口令|[:：]\s*$`)
+
+// CommandRecordFunc 记录还原出的命令, 会话、用户、资产信息由调用方补充
+type CommandRecordFunc func(cmd *entity.Command)
+
+// CommandReviewFunc 发起命令审批并等待结果, msg 为展示给用户的审批结果
+type CommandReviewFunc func(ctx context.Context, command string, acl *entity.CommandFilterACL) (approved bool, msg string)
+
+// ZmodemRecordFunc 记录 rz/sz 传输的文件, file 为 nil 表示传输在开始前被拒绝
+type ZmodemRecordFunc func(transferType string, file *zmodem.ZFileInfo, success bool, msg string)
+
+// ZmodemPolicy 会话中 rz/sz 传输的权限, MaxSize 为 0 表示不限制文件大小
+type ZmodemPolicy struct {
+	Upload   bool
+	Download bool
+	MaxSize  int64
+}
+
+type reviewResult struct {
+	approved bool
+	msg      string
+}
+
+func NewParser(id string, acls entity.CommandFilterACLs, recordFunc CommandRecordFunc, reviewFunc CommandReviewFunc) *Parser {
+	p := &Parser{
+		id:            id,
+		cmdFilterACLs: acls,
+		recordFunc:    recordFunc,
+		reviewFunc:    reviewFunc,
+		promptStale:   true,
+		reviewChan:    make(chan reviewResult, 1),
+		zmodemParser:  &zmodem.ZmodemParser{},
+	}
+	p.emulator.onLineFeed = p.onLineFeed
+	return p
+}
+
+// Parser 根据用户输入和服务端回显还原用户执行的命令, 回车时按命令过滤规则拦截
+type Parser struct {
+	id            string
+	cmdFilterACLs entity.CommandFilterACLs
+	recordFunc    CommandRecordFunc
+	reviewFunc    CommandReviewFunc
+
+	emulator lineEmulator
+
+	// prompt 用户开始输入前光标所在行的内容, 回车后整行去掉该前缀即为命令
+	prompt      string
+	promptStale bool
+
+	// commandPending 用户已回车, 等待服务端回显换行
+	commandPending bool
+	commandTime    time.Time
+	commandAction  string
+
+	// 已还原的命令, 收集其输出直至用户再次输入
+	current *entity.Command
+	output  strings.Builder
+
+	// 等待命令审批期间丢弃用户输入, Ctrl-C 撤销审批
+	reviewCancel context.CancelFunc
+	reviewChan   chan reviewResult
+	reviewInput  []byte
+	reviewCmd    string
+
+	// 用户输入按回车 (\r 或 \n) 拆分暂存, 回车前的输入回显完成后再处理回车;
+	// 一次粘贴多行时逐行发送, 上一行的输出停顿后再发送下一行
+	held       []byte
+	pacing     bool
+	unechoed   bool // 已发送的输入尚未收到服务端输出
+	typed      bool // 提示符之后用户输入过内容
+	lastOutput time.Time
+	waitStart  time.Time
+	waitTimer  *time.Timer
+
+	// altScreenWarned 本次进入全屏程序后已提示过回车被拦截
+	altScreenWarned bool
+
+	// ZMODEM 传输期间数据原样转发, 不解析命令
+	zmodemParser     *zmodem.ZmodemParser
+	zmodemPolicy     ZmodemPolicy
+	zmodemRecordFunc ZmodemRecordFunc
+	zmodemFile       *zmodem.ZFileInfo
+	// 服务端发出初始帧后等待用户终端回复握手的传输方向及开始等待的时间
+	zmodemPending   string
+	zmodemPendingAt time.Time
+	// 当前文件实际转发的字节数
+	zmodemRelayed int64
+
+	// paused 会话被管理员锁定时丢弃所有用户的输入
+	paused func() bool
+}
+
+func (p *Parser) isPaused() bool {
+	return p.paused != nil && p.paused()
+}
+
+func (p *Parser) InZmodemSession() bool {
+	return p.zmodemParser.IsStartSession()
+}
+
+func (p *Parser) ParseStream(userInChan chan *exchange.RoomMessage, srvInChan <-chan []byte, closed <-chan struct{}) (userOut, srvOut <-chan []byte) {
+	userOutputChan := make(chan []byte, 1)
+	srvOutputChan := make(chan []byte, 1)
+	go func() {
+		defer func() {
+			if p.reviewCancel != nil {
+				p.reviewCancel()
+			}
+			p.stopInputWait()
+			p.flushCommand()
+			if p.InZmodemSession() {
+				p.zmodemParser.Cancel()
+				p.finishZmodemFile(false, "session closed")
+			}
+			close(userOutputChan)
+			close(srvOutputChan)
+		}()
+		// send 返回 false 表示会话已结束
+		send := func(ch chan []byte, b []byte) bool {
+			if len(b) == 0 {
+				return true
+			}
+			select {
+			case <-closed:
+				return false
+			case ch <- b:
+				return true
+			}
+		}
+		for {
+			select {
+			case <-closed:
+				return
+			case msg, ok := <-userInChan:
+				if !ok {
+					return
+				}
+				var b []byte
+				switch msg.Event {
+				case exchange.DataEvent:
+					// 只读用户的输入以及锁定期间的输入不转发给服务端, ZMODEM 传输期间只接收会话创建者的输入
+					if msg.Meta.Writable && !p.isPaused() && (msg.Meta.Primary || !p.InZmodemSession()) {
+						b = msg.Body
+					}
+				}
+				if len(b) == 0 {
+					continue
+				}
+				b, notice := p.parseInput(b)
+				if !send(srvOutputChan, notice) || !send(userOutputChan, b) {
+					return
+				}
+
+			case b, ok := <-srvInChan:
+				if !ok {
+					return
+				}
+				b, reply := p.parseOutput(b)
+				if !send(userOutputChan, reply) || !send(srvOutputChan, b) {
+					return
+				}
+
+			case result := <-p.reviewChan:
+				b, notice := p.finishReview(result)
+				if !send(srvOutputChan, notice) || !send(userOutputChan, b) {
+					return
+				}
+
+			case <-p.inputWaitC():
+				b, notice := p.drainInput(time.Now())
+				if !send(srvOutputChan, notice) || !send(userOutputChan, b) {
+					return
+				}
+			}
+		}
+	}()
+	return userOutputChan, srvOutputChan
+}
+
+// parseInput 返回需要发送给服务端的数据和需要展示给用户的提示
+func (p *Parser) parseInput(b []byte) ([]byte, []byte) {
+	if p.InZmodemSession() {
+		return p.parseZmodemInput(b)
+	}
+	if transferType := p.zmodemPending; transferType != "" {
+		p.zmodemPending = ""
+		if time.Since(p.zmodemPendingAt) <= zmodemHandshakeTimeout {
+			if zmodem.IsHandshake(transferType, b) {
+				p.zmodemParser.Start(transferType)
+				return p.parseZmodemInput(b)
+			}
+			// 用户终端不支持 ZMODEM 时只能按 Ctrl-C 退出
+			if len(bytes.Trim(b, string(charCtrlC))) == 0 {
+				return zmodem.AbortSession, nil
+			}
+		}
+	}
+	b = p.zmodemParser.TrimOverAndOut(b)
+	if p.reviewCancel != nil {
+		if bytes.IndexByte(b, charCtrlC) >= 0 {
+			p.reviewCancel()
+		}
+		return nil, nil
+	}
+	now := time.Now()
+	if len(p.held) == 0 {
+		p.waitStart = now
+	}
+	p.held = append(p.held, b...)
+	return p.drainInput(now)
+}
+
+// drainInput 依次处理暂存的用户输入, 回车需等待此前输入的回显, 返回需要发送给服务端的数据和需要展示给用户的提示
+func (p *Parser) drainInput(now time.Time) ([]byte, []byte) {
+	p.stopInputWait()
+	var out, notice []byte
+	for len(p.held) > 0 && p.reviewCancel == nil {
+		// 全屏程序及 ZMODEM 传输中的输入不是命令
+		if p.InZmodemSession() || (p.emulator.altScreen && !p.cmdFilterACLs.Restricted()) {
+			out = append(out, p.held...)
+			p.held = nil
+			break
+		}
+		if p.emulator.altScreen {
+			// 全屏程序中无法还原命令, 存在拒绝或复核规则时丢弃回车,
+			// 避免通过 vim 的 :! 或伪造的备用屏幕切换执行命令
+			out = append(out, bytes.Map(dropSubmitKey, p.held)...)
+			if !p.altScreenWarned && bytes.ContainsAny(p.held, "\r\n") {
+				p.altScreenWarned = true
+				msg := "Enter is forbidden in full screen programs by command filter rules"
+				notice = append(notice, []byte(utils.CharNewLine+utils.WrapperWarn(msg)+utils.CharNewLine)...)
+			}
+			p.held = nil
+			break
+		}
+		p.altScreenWarned = false
+		index := bytes.IndexAny(p.held, "\r\n")
+		if index != 0 {
+			if p.pacing && !p.inputReady(now) {
+				p.armInputWait(now)
+				break
+			}
+			if index < 0 {
+				index = len(p.held)
+			}
+			p.flushCommand()
+			p.capturePrompt()
+			out = append(out, p.held[:index]...)
+			p.held = p.held[index:]
+			p.typed = true
+			p.pacing = false
+			p.markSent(now)
+			continue
+		}
+		if !p.inputReady(now) {
+			p.armInputWait(now)
+			break
+		}
+		p.flushCommand()
+		p.capturePrompt()
+		b := p.held[:1]
+		p.held = p.held[1:]
+		b, msg := p.submit(b, p.unechoed)
+		notice = append(notice, msg...)
+		if len(b) > 0 {
+			out = append(out, b...)
+			p.markSent(now)
+			p.pacing = true
+		}
+	}
+	if len(p.held) == 0 {
+		p.pacing = false
+	}
+	return out, notice
+}
+
+func dropSubmitKey(r rune) rune {
+	if r == '\r' || r == '\n' {
+		return -1
+	}
+	return r
+}
+
+// submit 处理回车, unknown 表示此前的输入没有回显, 返回需要发送给服务端的数据和需要展示给用户的提示
+func (p *Parser) submit(b []byte, unknown bool) ([]byte, []byte) {
+	command := p.currentCommand()
+	// 有输入但没有回显或无法还原命令时, 存在拒绝或复核规则则拒绝执行.
+	// 密码提示后的输入不回显, 行内容没有变化时放行; 关闭回显并将提示符改为密码提示样式仍可绕过
+	if p.typed && (unknown || command == "") {
+		if !p.cmdFilterACLs.Restricted() || (command == "" && noEchoPromptPattern.MatchString(p.prompt)) {
+			p.markCommandPending("")
+			return b, nil
+		}
+		p.held = nil
+		p.recordCommand(command, "", entity.CommandFilterDeny, time.Now())
+		msg := "Command can not be recognized and is forbidden by command filter rules"
+		return p.cleanLineInput(b), []byte(utils.CharNewLine + utils.WrapperWarn(msg))
+	}
+	acl := p.cmdFilterACLs.Match(command)
+	if command == "" || acl == nil {
+		p.markCommandPending("")
+		return b, nil
+	}
+	switch acl.Action {
+	case entity.CommandFilterDeny:
+		// 多行粘贴时丢弃被拒绝命令之后的输入
+		p.held = nil
+		p.recordCommand(command, "", entity.CommandFilterDeny, time.Now())
+		msg := fmt.Sprintf("Command `%s` is forbidden by rule %s", command, acl.Name)
+		return p.cleanLineInput(b), []byte(utils.CharNewLine + utils.WrapperWarn(msg))
+	case entity.CommandFilterWarn:
+		p.markCommandPending(entity.CommandFilterWarn)
+		msg := fmt.Sprintf("Warning: command `%s` matched rule %s and has been recorded", command, acl.Name)
+		return b, []byte(utils.CharNewLine + utils.WrapperWarn(msg))
+	case entity.CommandFilterReview:
+		if p.reviewFunc == nil {
+			break
+		}
+		ctx, cancel := context.WithCancel(context.Background())
+		p.reviewCancel = cancel
+		p.reviewInput = b
+		p.reviewCmd = command
+		go func() {
+			approved, msg := p.reviewFunc(ctx, command, acl)
+			p.reviewChan <- reviewResult{approved: approved, msg: msg}
+		}()
+		msg := fmt.Sprintf("Command `%s` requires approval by rule %s, waiting for review (Ctrl-C to cancel)",
+			command, acl.Name)
+		return nil, []byte(utils.CharNewLine + utils.WrapperString(msg, utils.Green) + utils.CharNewLine)
+	}
+	p.markCommandPending("")
+	return b, nil
+}
+
+// capturePrompt 上一行结束后用户首次输入时, 光标所在行的内容即为提示符
+func (p *Parser) capturePrompt() {
+	if p.promptStale {
+		p.prompt = p.emulator.Line()
+		p.promptStale = false
+		p.typed = false
+	}
+}
+
+func (p *Parser) markSent(now time.Time) {
+	p.unechoed = true
+	p.waitStart = now
+}
+
+// inputReady 已收到回显且输出停顿, 或已等待超时
+func (p *Parser) inputReady(now time.Time) bool {
+	if now.Sub(p.waitStart) >= echoWaitTimeout {
+		return true
+	}
+	return !p.unechoed && now.Sub(p.lastOutput) >= echoSettleDelay
+}
+
+func (p *Parser) armInputWait(now time.Time) {
+	deadline := p.waitStart.Add(echoWaitTimeout)
+	if settle := p.lastOutput.Add(echoSettleDelay); !p.unechoed && settle.Before(deadline) {
+		deadline = settle
+	}
+	p.stopInputWait()
+	p.waitTimer = time.NewTimer(deadline.Sub(now))
+}
+
+func (p *Parser) stopInputWait() {
+	if p.waitTimer != nil {
+		p.waitTimer.Stop()
+		p.waitTimer = nil
+	}
+}
+
+// inputWaitC 未在等待回显时返回 nil, select 时不会被选中
+func (p *Parser) inputWaitC() <-chan time.Time {
+	if p.waitTimer == nil {
+		return nil
+	}
+	return p.waitTimer.C
+}
+
+func (p *Parser) finishReview(result reviewResult) ([]byte, []byte) {
+	p.reviewCancel()
+	b, command := p.reviewInput, p.reviewCmd
+	p.reviewCancel, p.reviewInput, p.reviewCmd = nil, nil, ""
+	if result.approved {
+		p.markCommandPending(entity.CommandFilterReview)
+		now := time.Now()
+		p.markSent(now)
+		// 继续处理审批期间暂存的多行输入
+		more, notice := p.drainInput(now)
+		return append(b, more...), append([]byte(utils.WrapperString(result.msg, utils.Green)), notice...)
+	}
+	p.recordCommand(command, result.msg, entity.CommandFilterReview, time.Now())
+	p.held = nil
+	return p.cleanLineInput(b), []byte(utils.WrapperWarn(result.msg))
+}
+
+// cleanLineInput 清空服务端已输入的命令并发送回车, 让 shell 重新输出提示符
+func (p *Parser) cleanLineInput(b []byte) []byte {
+	p.promptStale = true
+	return append([]byte{utils.CharCleanLine}, b...)
+}
+
+func (p *Parser) markCommandPending(action string) {
+	p.commandPending = true
+	p.commandTime = time.Now()
+	p.commandAction = action
+}
+
+// currentCommand 回车前光标所在行去掉提示符即为待执行的命令
+func (p *Parser) currentCommand() string {
+	return strings.TrimSpace(strings.TrimPrefix(p.emulator.Line(), p.prompt))
+}
+
+// parseOutput 返回需要展示给用户的数据和需要回复给服务端的数据
+func (p *Parser) parseOutput(b []byte) ([]byte, []byte) {
+	p.unechoed = false
+	p.lastOutput = time.Now()
+	if len(p.held) > 0 {
+		p.armInputWait(p.lastOutput)
+	}
+	if p.InZmodemSession() {
+		return p.parseZmodemOutput(b)
+	}
+	if transferType := zmodem.DetectTransfer(b); transferType != "" {
+		if !p.zmodemAllowed(transferType) {
+			msg := fmt.Sprintf("Zmodem %s is not allowed", transferType)
+			p.recordZmodem(transferType, nil, false, msg)
+			return []byte(utils.CharNewLine + utils.WrapperWarn(msg) + utils.CharNewLine), zmodem.AbortSession
+		}
+		// 等待用户终端回复握手后再进入传输模式, 避免输出的普通数据绕过命令过滤
+		p.flushCommand()
+		p.zmodemPending = transferType
+		p.zmodemPendingAt = time.Now()
+		return b, nil
+	}
+	p.emulator.Feed(b)
+	return b, nil
+}
+
+func (p *Parser) zmodemAllowed(transferType string) bool {
+	if transferType == zmodem.TransferUpload {
+		return p.zmodemPolicy.Upload
+	}
+	return p.zmodemPolicy.Download
+}
+
+func (p *Parser) parseZmodemOutput(b []byte) ([]byte, []byte) {
+	if msg, ok := p.checkZmodemFiles(b, true); !ok {
+		return []byte(string(zmodem.AbortSession) + utils.CharNewLine + utils.WrapperWarn(msg) + utils.CharNewLine),
+			zmodem.AbortSession
+	}
+	return b, nil
+}
+
+// parseZmodemInput 返回需要发送给服务端的数据和需要展示给用户的提示
+func (p *Parser) parseZmodemInput(b []byte) ([]byte, []byte) {
+	// 用户终端不支持 ZMODEM 时只能按 Ctrl-C 退出
+	if !p.zmodemParser.FileStarted() && len(bytes.Trim(b, string(charCtrlC))) == 0 {
+		p.zmodemParser.Cancel()
+		p.finishZmodemFile(false, "canceled by user")
+		return zmodem.AbortSession, nil
+	}
+	if msg, ok := p.checkZmodemFiles(b, false); !ok {
+		return zmodem.AbortSession,
+			[]byte(string(zmodem.AbortSession) + utils.CharNewLine + utils.WrapperWarn(msg) + utils.CharNewLine)
+	}
+	return b, nil
+}
+
+// checkZmodemFiles 解析传输的文件并检查大小, 超出限制时中止传输并返回提示
+func (p *Parser) checkZmodemFiles(b []byte, fromServer bool) (string, bool) {
+	files := p.zmodemParser.Parse(b, fromServer)
+	for _, file := range files {
+		// 发送方开始发送下一个文件, 上一个文件已传输完成
+		p.finishZmodemFile(true, "")
+		if maxSize := p.zmodemPolicy.MaxSize; maxSize > 0 && file.Size > maxSize {
+			p.zmodemParser.Cancel()
+			msg := fmt.Sprintf("File %s (%d bytes) exceeds the max size %d bytes, transfer aborted",
+				file.Filename, file.Size, maxSize)
+			p.recordZmodem(p.zmodemParser.TransferType(), file, false, msg)
+			return msg, false
+		}
+		p.zmodemFile = file
+		p.zmodemRelayed = 0
+	}
+	// 声明的大小可能不实, 按实际转发的字节数检查, 预留转义和帧头的开销
+	if maxSize := p.zmodemPolicy.MaxSize; maxSize > 0 && p.zmodemFile != nil &&
+		fromServer == (p.zmodemParser.TransferType() == zmodem.TransferDownload) {
+		p.zmodemRelayed += int64(len(b))
+		if p.zmodemRelayed > maxSize+maxSize/16+zmodemFrameOverhead {
+			p.zmodemParser.Cancel()
+			msg := fmt.Sprintf("File %s exceeds the max size %d bytes, transfer aborted",
+				p.zmodemFile.Filename, maxSize)
+			p.finishZmodemFile(false, msg)
+			return msg, false
+		}
+	}
+	if !p.InZmodemSession() {
+		if p.zmodemParser.IsCanceled() {
+			p.finishZmodemFile(false, "transfer canceled")
+		} else {
+			p.finishZmodemFile(true, "")
+		}
+	}
+	return "", true
+}
+
+func (p *Parser) finishZmodemFile(success bool, msg string) {
+	if p.zmodemFile == nil {
+		return
+	}
+	p.recordZmodem(p.zmodemParser.TransferType(), p.zmodemFile, success, msg)
+	p.zmodemFile = nil
+}
+
+func (p *Parser) recordZmodem(transferType string, file *zmodem.ZFileInfo, success bool, msg string) {
+	if p.zmodemRecordFunc == nil {
+		return
+	}
+	p.zmodemRecordFunc(transferType, file, success, msg)
+}
+
+func (p *Parser) onLineFeed(line string) {
+	p.promptStale = true
+	if p.commandPending {
+		p.commandPending = false
+		input := strings.TrimSpace(strings.TrimPrefix(line, p.prompt))
+		if input != "" {
+			p.current = &entity.Command{
+				Input:        input,
+				FilterAction: p.commandAction,
+				Timestamp:    p.commandTime,
+			}
+			p.output.Reset()
+		}
+		return
+	}
+	if p.current == nil || p.emulator.altScreen {
+		return
+	}
+	if remain := commandOutputMaxSize - p.output.Len(); remain > 0 {
+		line = strings.TrimRight(line, " ") + "\n"
+		if len(line) > remain {
+			line = line[:remain]
+		}
+		p.output.WriteString(line)
+	}
+}
+
+func (p *Parser) recordCommand(input, output, action string, timestamp time.Time) {
+	if p.recordFunc == nil {
+		return
+	}
+	p.recordFunc(&entity.Command{
+		Input:        input,
+		Output:       output,
+		FilterAction: action,
+		Timestamp:    timestamp,
+	})
+}
+
+func (p *Parser) flushCommand() {
+	if p.current == nil {
+		return
+	}
+	if p.recordFunc != nil {
+		p.current.Output = strings.TrimRight(p.output.String(), "\n")
+		p.recordFunc(p.current)
+	}
+	p.current = nil
+	p.output.Reset()
+}
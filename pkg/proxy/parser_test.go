@@ -0,0 +1,404 @@
+package proxy
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/zmodem"
+)
+
+// parserStep 依次模拟用户输入、服务端输出以及等待回显
+type parserStep struct {
+	in   string
+	out  string
+	wait time.Duration
+	// 本步骤结束时累计发送给服务端的数据
+	sent string
+}
+
+type parserResult struct {
+	sent     string
+	notice   string
+	commands []string
+}
+
+func runParser(t *testing.T, p *Parser, steps []parserStep) parserResult {
+	t.Helper()
+	var res parserResult
+	p.recordFunc = func(cmd *entity.Command) {
+		res.commands = append(res.commands, cmd.Input+" => "+cmd.Output)
+	}
+	for i, step := range steps {
+		var toSrv, toUser []byte
+		switch {
+		case step.in != "":
+			toSrv, toUser = p.parseInput([]byte(step.in))
+		case step.out != "":
+			_, toSrv = p.parseOutput([]byte(step.out))
+		default:
+			toSrv, toUser = p.drainInput(time.Now().Add(step.wait))
+		}
+		res.sent += string(toSrv)
+		res.notice += string(toUser)
+		if step.sent != "" && res.sent != step.sent {
+			t.Fatalf("step %d: sent %q, want %q", i, res.sent, step.sent)
+		}
+	}
+	p.stopInputWait()
+	p.flushCommand()
+	return res
+}
+
+const (
+	settled  = 100 * time.Millisecond
+	noEchoed = 3 * time.Second
+)
+
+func TestParserCommandRecord(t *testing.T) {
+	tests := []struct {
+		name     string
+		steps    []parserStep
+		sent     string
+		commands []string
+	}{
+		{
+			name: "typed key by key",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "l"}, {out: "l"}, {in: "s"}, {out: "s"},
+				{in: "\r", sent: "ls"},
+				{wait: settled, sent: "ls\r"},
+				{out: "\r\na.txt\r\n$ "},
+			},
+			sent:     "ls\r",
+			commands: []string{"ls => a.txt"},
+		},
+		{
+			name: "enter waits for the echo",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "ls\r", sent: "ls"},
+				{wait: settled, sent: "ls"},
+				{out: "ls"},
+				{wait: settled, sent: "ls\r"},
+				{out: "\r\na.txt\r\n$ "},
+			},
+			sent:     "ls\r",
+			commands: []string{"ls => a.txt"},
+		},
+		{
+			name: "edited with backspace and tab",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "cat /etc/hox"}, {out: "cat /etc/hox"},
+				{in: "\x7f"}, {out: "\b\x1b[K"},
+				{in: "\t"}, {out: "stname "},
+				{in: "\r"},
+				{wait: settled},
+				{out: "\r\nkubejump\r\n$ "},
+			},
+			sent:     "cat /etc/hox\x7f\t\r",
+			commands: []string{"cat /etc/hostname => kubejump"},
+		},
+		{
+			name: "multi line paste is sent line by line",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "echo a\recho b\r", sent: "echo a"},
+				{out: "echo a"},
+				{wait: settled, sent: "echo a\r"},
+				{wait: settled, sent: "echo a\r"},
+				{out: "\r\na\r\n$ "},
+				{wait: settled, sent: "echo a\recho b"},
+				{out: "echo b"},
+				{wait: settled, sent: "echo a\recho b\r"},
+				{out: "\r\nb\r\n$ "},
+			},
+			sent:     "echo a\recho b\r",
+			commands: []string{"echo a => a", "echo b => b"},
+		},
+		{
+			name: "ctrl-j submits the command",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "pwd\n", sent: "pwd"},
+				{out: "pwd"},
+				{wait: settled, sent: "pwd\n"},
+				{out: "\r\n/root\r\n$ "},
+			},
+			sent:     "pwd\n",
+			commands: []string{"pwd => /root"},
+		},
+		{
+			name: "enter is sent after waiting when there is no echo",
+			steps: []parserStep{
+				{out: "Password: "},
+				{in: "secret\r", sent: "secret"},
+				{wait: settled, sent: "secret"},
+				{wait: noEchoed, sent: "secret\r"},
+				{out: "\r\n$ "},
+			},
+			sent: "secret\r",
+		},
+		{
+			name: "input in full screen program is not parsed",
+			steps: []parserStep{
+				{out: "\x1b[?1049h~"},
+				{in: ":wq\r", sent: ":wq\r"},
+				{out: "\x1b[?1049l\r$ "},
+			},
+			sent: ":wq\r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser("test", nil, nil, nil)
+			res := runParser(t, p, tt.steps)
+			if res.sent != tt.sent {
+				t.Errorf("sent %q, want %q", res.sent, tt.sent)
+			}
+			if !reflect.DeepEqual(res.commands, tt.commands) {
+				t.Errorf("commands %q, want %q", res.commands, tt.commands)
+			}
+		})
+	}
+}
+
+func TestParserCommandFilter(t *testing.T) {
+	acl := &entity.CommandFilterACL{
+		Name:    "no-rm",
+		Type:    entity.CommandFilterTypeCommand,
+		Content: "rm",
+		Action:  entity.CommandFilterDeny,
+	}
+	if err := acl.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		steps  []parserStep
+		sent   string
+		denied bool
+	}{
+		{
+			name: "allowed command",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "ls\r"}, {out: "ls"}, {wait: settled},
+				{out: "\r\na.txt\r\n$ "},
+			},
+			sent: "ls\r",
+		},
+		{
+			name: "enter in the same chunk",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "rm -rf /\r"}, {out: "rm -rf /"}, {wait: settled},
+			},
+			sent:   "rm -rf /\x15\r",
+			denied: true,
+		},
+		{
+			name: "denied line in multi line paste drops the rest",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "ls\rrm -rf /\recho done\r"}, {out: "ls"}, {wait: settled},
+				{out: "\r\na.txt\r\n$ "}, {wait: settled},
+				{out: "rm -rf /"}, {wait: settled},
+				{wait: noEchoed},
+			},
+			sent:   "ls\rrm -rf /\x15\r",
+			denied: true,
+		},
+		{
+			name: "ctrl-j",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "rm -rf /\n"}, {out: "rm -rf /"}, {wait: settled},
+			},
+			sent:   "rm -rf /\x15\n",
+			denied: true,
+		},
+		{
+			name: "no echo",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "rm -rf /\r"}, {wait: noEchoed},
+			},
+			sent:   "rm -rf /\x15\r",
+			denied: true,
+		},
+		{
+			name: "empty enter without input",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "\r"}, {wait: settled},
+			},
+			sent: "\r",
+		},
+		{
+			name: "password prompt",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "sudo ls\r"}, {out: "sudo ls"}, {wait: settled},
+				{out: "\r\n[sudo] password for alice: "},
+				{in: "secret\r"}, {wait: noEchoed},
+				{out: "\r\na.txt\r\n$ "},
+			},
+			sent: "sudo ls\rsecret\r",
+		},
+		{
+			name: "custom read -s prompt",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "read -s -p 'Token: ' t\r"}, {out: "read -s -p 'Token: ' t"}, {wait: settled},
+				{out: "\r\nToken: "},
+				{in: "abc\r"}, {wait: noEchoed},
+			},
+			sent: "read -s -p 'Token: ' t\rabc\r",
+		},
+		{
+			name: "echoed command at password prompt",
+			steps: []parserStep{
+				{out: "Password: "},
+				{in: "rm -rf /\r"}, {out: "rm -rf /"}, {wait: settled},
+			},
+			sent:   "rm -rf /\x15\r",
+			denied: true,
+		},
+		{
+			name: "full screen program",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "vim\r"}, {out: "vim"}, {wait: settled},
+				{out: "\r\n\x1b[?1049h"},
+				{in: ":!rm -rf /\r"}, {in: "\r"},
+			},
+			sent:   "vim\r:!rm -rf /",
+			denied: true,
+		},
+		{
+			name: "fake alternate screen",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "printf '\\033[?1049h'\r"}, {out: "printf '\\033[?1049h'"}, {wait: settled},
+				{out: "\r\n\x1b[?1049h$ "},
+				{in: "rm -rf /\r"},
+			},
+			sent:   "printf '\\033[?1049h'\rrm -rf /",
+			denied: true,
+		},
+		{
+			name: "leave full screen program",
+			steps: []parserStep{
+				{out: "$ "},
+				{in: "vim\r"}, {out: "vim"}, {wait: settled},
+				{out: "\r\n\x1b[?1049h"},
+				{in: "ZZ"}, {out: "\x1b[?1049l$ "},
+				{in: "ls\r"}, {out: "ls"}, {wait: settled},
+			},
+			sent: "vim\rZZls\r",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewParser("test", entity.CommandFilterACLs{acl}, nil, nil)
+			res := runParser(t, p, tt.steps)
+			if res.sent != tt.sent {
+				t.Errorf("sent %q, want %q", res.sent, tt.sent)
+			}
+			if denied := strings.Contains(res.notice, "forbidden"); denied != tt.denied {
+				t.Errorf("denied %v, want %v, notice %q", denied, tt.denied, res.notice)
+			}
+		})
+	}
+}
+
+func TestParserFullScreenWithoutRestrictedRules(t *testing.T) {
+	acl := &entity.CommandFilterACL{
+		Name:    "warn-rm",
+		Type:    entity.CommandFilterTypeCommand,
+		Content: "rm",
+		Action:  entity.CommandFilterWarn,
+	}
+	if err := acl.Compile(); err != nil {
+		t.Fatal(err)
+	}
+	p := NewParser("test", entity.CommandFilterACLs{acl}, nil, nil)
+	res := runParser(t, p, []parserStep{
+		{out: "$ "},
+		{in: "vim\r"}, {out: "vim"}, {wait: settled},
+		{out: "\r\n\x1b[?1049h"},
+		{in: ":w\r"},
+	})
+	if res.sent != "vim\r:w\r" || res.notice != "" {
+		t.Errorf("sent %q notice %q, want input passed through", res.sent, res.notice)
+	}
+}
+
+func TestParserZmodem(t *testing.T) {
+	const (
+		zrqinit = "**\x18B00000000000000\r\x8a\x11"
+		zrinit  = "**\x18B0100000023be50\r\x8a\x11"
+		// 未声明大小的 ZFILE 帧
+		zfile = "*\x18A\x04\x00\x00\x00\x00\x00\x00a.bin\x00\x00\x18k"
+	)
+	acl := &entity.CommandFilterACL{
+		Name:    "no-rm",
+		Type:    entity.CommandFilterTypeCommand,
+		Content: "rm",
+		Action:  entity.CommandFilterDeny,
+	}
+	if err := acl.Compile(); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("init frame in output without handshake", func(t *testing.T) {
+		p := NewParser("test", entity.CommandFilterACLs{acl}, nil, nil)
+		p.zmodemPolicy = ZmodemPolicy{Download: true}
+		res := runParser(t, p, []parserStep{
+			{out: "$ "}, {out: zrqinit}, {out: "\r\n$ "},
+			{in: "rm -rf /\r"}, {out: "rm -rf /"}, {wait: settled},
+		})
+		if p.InZmodemSession() {
+			t.Fatal("zmodem session started without handshake")
+		}
+		if res.sent != "rm -rf /\x15\r" {
+			t.Errorf("sent %q, want the command denied", res.sent)
+		}
+	})
+
+	t.Run("handshake starts the session", func(t *testing.T) {
+		p := NewParser("test", nil, nil, nil)
+		p.zmodemPolicy = ZmodemPolicy{Download: true}
+		runParser(t, p, []parserStep{{out: "$ "}, {out: zrqinit}, {in: zrinit, sent: zrinit}})
+		if !p.InZmodemSession() {
+			t.Fatal("zmodem session not started after handshake")
+		}
+	})
+
+	t.Run("relayed bytes exceed the max size", func(t *testing.T) {
+		var records []string
+		p := NewParser("test", nil, nil, nil)
+		p.zmodemPolicy = ZmodemPolicy{Download: true, MaxSize: 1024}
+		p.zmodemRecordFunc = func(_ string, file *zmodem.ZFileInfo, success bool, msg string) {
+			records = append(records, msg)
+		}
+		p.parseOutput([]byte(zrqinit))
+		p.parseInput([]byte(zrinit))
+		p.parseOutput([]byte(zfile))
+		if !p.InZmodemSession() {
+			t.Fatal("zmodem session not started")
+		}
+		_, reply := p.parseOutput(make([]byte, 8192))
+		if string(reply) != string(zmodem.AbortSession) || p.InZmodemSession() {
+			t.Fatalf("transfer not aborted, reply %q", reply)
+		}
+		if len(records) != 1 || !strings.Contains(records[0], "exceeds the max size") {
+			t.Errorf("records %q", records)
+		}
+	})
+}
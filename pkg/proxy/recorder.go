@@ -0,0 +1,101 @@
+package proxy
+
+import (
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/asciinema"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const replayFileExt = ".cast"
+
+// ReplayRecorder 以 asciinema v2 格式记录会话的终端输出
+type ReplayRecorder struct {
+	SessionID   string
+	Target      string
+	AbsFilePath string
+
+	file   *os.File
+	writer *asciinema.Writer
+	err    error
+}
+
+func NewReplayRecorder(sessionID, target string, width, height int, timestamp time.Time) *ReplayRecorder {
+	recorder := &ReplayRecorder{
+		SessionID: sessionID,
+		Target:    target,
+	}
+	if config.GetConf().TerminalConf.ReplayStorage.TypeName == entity.ReplayStorageNull {
+		return recorder
+	}
+
+	dateFolder := filepath.Join(config.GetConf().ReplayFolderPath, timestamp.Format("2006-01-02"))
+	if err := config.EnsureDirExist(dateFolder); err != nil {
+		recorder.err = err
+		klog.Errorf("Session[%s] create replay folder %s failed: %s", sessionID, dateFolder, err)
+		return recorder
+	}
+	recorder.AbsFilePath = filepath.Join(dateFolder, sessionID+replayFileExt)
+	fd, err := os.Create(recorder.AbsFilePath)
+	if err != nil {
+		recorder.err = err
+		klog.Errorf("Session[%s] create replay file %s failed: %s", sessionID, recorder.AbsFilePath, err)
+		return recorder
+	}
+	recorder.file = fd
+
+	options := make([]asciinema.Option, 0, 5)
+	options = append(options, asciinema.WithTitle(target))
+	options = append(options, asciinema.WithTimestamp(timestamp))
+	if width > 0 && height > 0 {
+		options = append(options, asciinema.WithWidth(width))
+		options = append(options, asciinema.WithHeight(height))
+	}
+	recorder.writer = asciinema.NewWriter(fd, options...)
+	if err = recorder.writer.WriteHeader(); err != nil {
+		recorder.fail(err)
+	}
+	return recorder
+}
+
+func (r *ReplayRecorder) isNullStorage() bool {
+	return r.writer == nil || r.err != nil
+}
+
+func (r *ReplayRecorder) fail(err error) {
+	r.err = err
+	klog.Errorf("Session[%s] write replay file %s failed: %s", r.SessionID, r.AbsFilePath, err)
+}
+
+func (r *ReplayRecorder) Record(p []byte) {
+	if r.isNullStorage() || len(p) == 0 {
+		return
+	}
+	if err := r.writer.WriteRow(p); err != nil {
+		r.fail(err)
+	}
+}
+
+func (r *ReplayRecorder) RecordResize(width, height int) {
+	if r.isNullStorage() {
+		return
+	}
+	if err := r.writer.WriteResize(width, height); err != nil {
+		r.fail(err)
+	}
+}
+
+func (r *ReplayRecorder) End() {
+	if r.file == nil {
+		return
+	}
+	if err := r.file.Close(); err != nil {
+		klog.Errorf("Session[%s] close replay file %s failed: %s", r.SessionID, r.AbsFilePath, err)
+		return
+	}
+	klog.Infof("Session[%s] replay file saved: %s", r.SessionID, r.AbsFilePath)
+	go uploadReplayFile(r.AbsFilePath)
+}
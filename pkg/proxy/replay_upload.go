@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"errors"
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/storage"
+	"github.com/toolkits/pkg/retry"
+	"io"
+	"io/fs"
+	"k8s.io/klog/v2"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	replayGzipExt = ".gz"
+
+	replayUploadRetries  = 3
+	replayUploadInterval = time.Second
+)
+
+// uploadReplayFile 压缩录像并上传到配置的存储, 上传失败时保留压缩文件等待下次启动重传
+func uploadReplayFile(castFile string) {
+	gzFile := castFile + replayGzipExt
+	if err := gzipFile(castFile, gzFile); err != nil {
+		klog.Errorf("Gzip replay file %s failed: %s", castFile, err)
+		return
+	}
+	if err := os.Remove(castFile); err != nil {
+		klog.Errorf("Remove replay file %s failed: %s", castFile, err)
+	}
+	_ = uploadGzipReplay(gzFile)
+}
+
+func uploadGzipReplay(gzFile string) error {
+	conf := config.GetConf()
+	replayConf := conf.TerminalConf.ReplayStorage
+	replayStorage, err := storage.NewReplayStorage(replayConf)
+	if err != nil {
+		klog.Errorf("Create replay storage failed: %s", err)
+		return err
+	}
+	target, err := filepath.Rel(conf.ReplayFolderPath, gzFile)
+	if err != nil {
+		target = filepath.Base(gzFile)
+	}
+	target = filepath.ToSlash(target)
+
+	err = retry.Retry(replayUploadRetries, replayUploadInterval, func() error {
+		err := replayStorage.Upload(gzFile, target)
+		var statusErr *storage.UploadStatusError
+		if errors.As(err, &statusErr) && !statusErr.Temporary() {
+			return retry.Stop{Err: err}
+		}
+		return err
+	})
+	if err != nil {
+		klog.Errorf("Upload replay %s to %s storage failed: %s", gzFile, replayStorage.TypeName(), err)
+		return err
+	}
+	klog.Infof("Upload replay %s to %s storage success", gzFile, replayStorage.TypeName())
+	// 本地存储未配置归档目录时, 录像直接保留在录像目录中
+	if replayStorage.TypeName() == entity.ReplayStorageLocal && replayConf.RootPath == "" {
+		return nil
+	}
+	if err = os.Remove(gzFile); err != nil && !os.IsNotExist(err) {
+		klog.Errorf("Remove uploaded replay %s failed: %s", gzFile, err)
+	}
+	return nil
+}
+
+// processStartTime 进程启动时间, 此后修改过的录像属于本进程中的会话
+var processStartTime = time.Now()
+
+// UploadFailedReplays 启动时重新上传录像目录中遗留的录像, 包括进程异常退出时未压缩的录像.
+// 与 ssh 服务同时运行, 跳过本进程启动后修改过的录像, 避免处理仍在录制或正在上传的文件
+func UploadFailedReplays() {
+	conf := config.GetConf()
+	replayConf := conf.TerminalConf.ReplayStorage
+	if replayConf.TypeName == entity.ReplayStorageNull ||
+		(replayConf.TypeName == entity.ReplayStorageLocal && replayConf.RootPath == "") {
+		return
+	}
+	err := filepath.WalkDir(conf.ReplayFolderPath, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			// 文件可能已被当前会话的上传流程删除
+			return nil
+		}
+		if !info.ModTime().Before(processStartTime) {
+			return nil
+		}
+		switch {
+		case strings.HasSuffix(path, replayFileExt):
+			klog.Infof("Upload failed replay %s", path)
+			uploadReplayFile(path)
+		case strings.HasSuffix(path, replayFileExt+replayGzipExt):
+			klog.Infof("Upload failed replay %s", path)
+			_ = uploadGzipReplay(path)
+		}
+		return nil
+	})
+	if err != nil {
+		klog.Errorf("Walk replay folder %s failed: %s", conf.ReplayFolderPath, err)
+	}
+}
+
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	gw := gzip.NewWriter(out)
+	if _, err = io.Copy(gw, in); err != nil {
+		_ = gw.Close()
+		_ = out.Close()
+		return err
+	}
+	if err = gw.Close(); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
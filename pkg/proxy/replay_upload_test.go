@@ -0,0 +1,94 @@
+package proxy
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/daicheng123/kubejump/config"
+	"github.com/daicheng123/kubejump/internal/entity"
+)
+
+func setupReplayUpload(t *testing.T, handler http.HandlerFunc) string {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	dir := t.TempDir()
+	config.GlobalConfig = &config.Config{
+		ReplayFolderPath: dir,
+		TerminalConf: &entity.TerminalConfig{
+			ReplayStorage: entity.ReplayConfig{TypeName: entity.ReplayStorageHTTP, URL: srv.URL + "/replays"},
+		},
+	}
+	t.Cleanup(func() { config.GlobalConfig = nil })
+	return dir
+}
+
+func TestUploadReplayFileGzipped(t *testing.T) {
+	const content = `{"version": 2}` + "\n" + `[0.1, "o", "ls\r\n"]` + "\n"
+	var gotPath, gotBody string
+	dir := setupReplayUpload(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPut {
+			t.Errorf("method = %s, want PUT", r.Method)
+		}
+		gotPath = r.URL.Path
+		gr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Errorf("request body is not gzipped: %s", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		body, _ := io.ReadAll(gr)
+		gotBody = string(body)
+	})
+	castFile := filepath.Join(dir, "2006-01-02", "sid"+replayFileExt)
+	if err := os.MkdirAll(filepath.Dir(castFile), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(castFile, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	uploadReplayFile(castFile)
+
+	if want := "/replays/2006-01-02/sid" + replayFileExt + replayGzipExt; gotPath != want {
+		t.Errorf("path = %s, want %s", gotPath, want)
+	}
+	if gotBody != content {
+		t.Errorf("body = %q, want %q", gotBody, content)
+	}
+	for _, f := range []string{castFile, castFile + replayGzipExt} {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s should be removed after upload", f)
+		}
+	}
+}
+
+func TestUploadGzipReplayStopsOnClientError(t *testing.T) {
+	var requests int
+	dir := setupReplayUpload(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusForbidden)
+	})
+	castFile := filepath.Join(dir, "sid"+replayFileExt)
+	if err := os.WriteFile(castFile, []byte("{}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	gzFile := castFile + replayGzipExt
+	if err := gzipFile(castFile, gzFile); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := uploadGzipReplay(gzFile); err == nil {
+		t.Fatal("upload should fail on 403")
+	}
+	if requests != 1 {
+		t.Errorf("requests = %d, want 1", requests)
+	}
+	if _, err := os.Stat(gzFile); err != nil {
+		t.Errorf("failed replay should be kept for retry on next start: %s", err)
+	}
+}
@@ -2,7 +2,9 @@ package proxy
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"github.com/daicheng123/kubejump/config"
 	"github.com/daicheng123/kubejump/internal/entity"
 	"github.com/daicheng123/kubejump/internal/service"
 	"github.com/daicheng123/kubejump/pkg/exchange"
@@ -10,6 +12,7 @@ import (
 	"github.com/daicheng123/kubejump/pkg/session"
 	"github.com/daicheng123/kubejump/pkg/srvconn"
 	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/daicheng123/kubejump/pkg/zmodem"
 	"github.com/toolkits/pkg/logger"
 	gossh "golang.org/x/crypto/ssh"
 	"k8s.io/klog/v2"
@@ -38,22 +41,37 @@ func NewProxyServer(conn UserConnection, jmsService *service.JMService, opts ...
 	//	assetName = connOpts.k8sContainer.K8sName(asset.Name)
 	//}
 	apiSession := &entity.Session{
-		ID:         utils.UUID(),
-		User:       user.String(),
-		LoginFrom:  entity.LabelField(conn.LoginFrom()),
-		RemoteAddr: conn.RemoteAddr(),
-		UserID:     int(user.ID),
-		Asset:      assetName,
-		AssetID:    int(asset.ID),
-		Type:       entity.NORMALType,
+		ID:          utils.UUID(),
+		User:        user.String(),
+		LoginFrom:   entity.LabelField(conn.LoginFrom()),
+		RemoteAddr:  conn.RemoteAddr(),
+		UserID:      int(user.ID),
+		Asset:       assetName,
+		ClusterName: asset.ClusterName,
+		Namespace:   asset.Namespace,
+		PodName:     asset.PodName,
+		DateStart:   time.Now(),
+		AssetID:     int(asset.ID),
+		Type:        entity.NORMALType,
+		ShareMode:   entity.ShareModeOff,
+	}
+	if connOpts.k8sContainer != nil {
+		apiSession.Container = connOpts.k8sContainer.Container
+	}
+	if connOpts.logs != nil {
+		apiSession.Type = entity.LOGSType
+	}
+	if config.GetConf().TerminalConf.EnableSessionShare && connOpts.shareMode != "" {
+		apiSession.ShareMode = connOpts.shareMode
 	}
 
 	return &ProxyServer{
-		ID:          apiSession.ID,
-		UserConn:    conn,
-		jmsService:  jmsService,
-		connOpts:    connOpts,
-		sessionInfo: apiSession,
+		ID:           apiSession.ID,
+		UserConn:     conn,
+		jmsService:   jmsService,
+		connOpts:     connOpts,
+		terminalConf: config.GetConf().TerminalConf,
+		sessionInfo:  apiSession,
 	}, nil
 }
 
@@ -67,6 +85,7 @@ type ProxyServer struct {
 	domainGateways     *entity.Domain
 	sessionInfo        *entity.Session
 	cacheSSHConnection *srvconn.SSHConnection
+	cmdFilterACLs      entity.CommandFilterACLs
 	keyboardMode       int32
 	OnSessionInfo      func(info *SessionInfo)
 	BroadcastEvent     func(event *exchange.RoomMessage)
@@ -79,7 +98,15 @@ func (s *ProxyServer) Proxy() {
 		}
 	}()
 
+	// 无法加载命令过滤规则时拒绝会话, 避免绕过命令拦截
+	if err := s.loadCommandFilters(); err != nil {
+		klog.Errorf("Session[%s] load command filters failed: %s", s.ID, err)
+		utils.IgnoreErrWriteString(s.UserConn, utils.WrapperWarn("Load command filters failed, connection refused"))
+		return
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
 	maxIdleTime := s.terminalConf.MaxIdleTime
 
@@ -131,7 +158,7 @@ func (s *ProxyServer) Proxy() {
 	srvCon, err := s.getServerConn()
 	if err != nil {
 		logger.Error(err)
-		//s.sendConnectErrorMsg(err)
+		s.sendConnectErrorMsg(err)
 		//if err2 := s.ConnectedFailedCallback(err); err2 != nil {
 		//	logger.Errorf("Conn[%s] update session err: %s", s.UserConn.ID(), err2)
 		//}
@@ -163,10 +190,42 @@ func (s *ProxyServer) Proxy() {
 		go s.OnSessionInfo(&info)
 	}
 
+	if s.sessionInfo.ShareMode != entity.ShareModeOff {
+		asset := s.connOpts.authInfo.Asset
+		exchange.RegisterRoomInfo(&exchange.RoomInfo{
+			Id:          s.ID,
+			User:        s.sessionInfo.User,
+			ClusterName: asset.ClusterName,
+			Namespace:   asset.Namespace,
+			PodName:     asset.PodName,
+			Labels:      asset.Labels,
+			ShareMode:   s.sessionInfo.ShareMode,
+			Created:     utils.NewNowUTCTime().String(),
+		})
+		defer exchange.UnRegisterRoomInfo(s.ID)
+	}
+
+	s.jmsService.CreateSession(context.Background(), s.sessionInfo)
+
 	//utils.IgnoreErrWriteWindowTitle(s.UserConn, s.connOpts.TerminalTitle())
 	if err = sw.Bridge(s.UserConn, srvCon); err != nil {
 		logger.Error(err)
 	}
+	s.finishSession(&sw)
+}
+
+// finishSession 记录会话结束时间、原因以及终断会话的管理员
+func (s *ProxyServer) finishSession(sw *SwitchSession) {
+	dateEnd := time.Now()
+	s.sessionInfo.DateEnd = &dateEnd
+	s.sessionInfo.ExitReason = entity.ExitReasonNormal
+	if sw.exitReason != "" {
+		s.sessionInfo.ExitReason = sw.exitReason
+	}
+	if sw.exitReason == entity.ExitReasonAdminTerminate {
+		s.sessionInfo.TerminatedBy = sw.loadOperator()
+	}
+	s.jmsService.FinishSession(context.Background(), s.sessionInfo)
 }
 
 //func (s *ProxyServer) createAvailableGateWay(domain *entity.Domain) *domainGateway {
@@ -305,8 +364,18 @@ func ReplaceURLHostAndPort(originUrl *url.URL, ip string, port int) string {
 	return newUrl.String()
 }
 
+// sendConnectErrorMsg 连接失败时提示用户失败原因
+func (s *ProxyServer) sendConnectErrorMsg(err error) {
+	asset := s.connOpts.authInfo.Asset
+	target := fmt.Sprintf("%s/%s", asset.Namespace, asset.PodName)
+	if info := s.connOpts.k8sContainer; info != nil && info.Container != "" {
+		target = fmt.Sprintf("%s/%s", target, info.Container)
+	}
+	msg := fmt.Sprintf("Connect to %s failed: %s", target, err)
+	utils.IgnoreErrWriteString(s.UserConn, utils.WrapperWarn(msg))
+}
+
 func (s *ProxyServer) getContainerConn(cluster *entity.ClusterConfig) (srvConn srvconn.ServerConnection, err error) {
-	info := s.connOpts.k8sContainer
 	//token := s.account.Secret
 	pty := s.UserConn.Pty()
 
@@ -314,51 +383,134 @@ func (s *ProxyServer) getContainerConn(cluster *entity.ClusterConfig) (srvConn s
 		Width:  pty.Window.Width,
 		Height: pty.Window.Height,
 	}
-	opts := make([]conn.ContainerFunc, 0, 5)
+	opts := s.containerOptions(cluster)
+	if s.connOpts.logs != nil {
+		srvConn, err = conn.NewLogsConnection(*s.connOpts.logs, opts...)
+		return
+	}
+	opts = append(opts, conn.ContainerPtyWin(win))
+	srvConn, err = conn.NewKubernetesConnection(opts...)
+	return
+}
+
+func (s *ProxyServer) containerOptions(cluster *entity.ClusterConfig) []conn.ContainerFunc {
+	info := s.connOpts.k8sContainer
+	opts := make([]conn.ContainerFunc, 0, 6)
 	opts = append(opts, conn.ContainerClusterConfig(cluster))
 	opts = append(opts, conn.ContainerName(info.Container))
 	opts = append(opts, conn.ContainerPodName(info.PodName))
 	opts = append(opts, conn.ContainerNamespace(info.Namespace))
-	opts = append(opts, conn.ContainerSkipTls(true))
-	opts = append(opts, conn.ContainerPtyWin(win))
-	srvConn, err = conn.NewKubernetesConnection(opts...)
-	return
+	opts = append(opts, conn.ContainerSkipTls(config.GetConf().K8sSkipTls))
+	return opts
+}
+
+func (s *ProxyServer) GetReplayRecorder() *ReplayRecorder {
+	win := s.UserConn.Pty().Window
+	target := fmt.Sprintf("%s@%s", s.connOpts.authInfo.User.Username, s.connOpts.authInfo.Asset)
+	if info := s.connOpts.k8sContainer; info != nil {
+		target = fmt.Sprintf("%s@%s/%s/%s", s.connOpts.authInfo.User.Username,
+			info.CLuster.ClusterName, info.Namespace, info.PodName)
+	}
+	return NewReplayRecorder(s.ID, target, win.Width, win.Height, time.Now())
 }
 
 func (s *ProxyServer) CheckPermissionExpired(now time.Time) bool {
 	return s.connOpts.authInfo.ExpireAt.IsExpired(now)
 }
 
-//func (s *ProxyServer) GetFilterParser() *Parser {
-//	var (
-//		enableUpload   bool
-//		enableDownload bool
-//	)
-//	actions := s.connOpts.authInfo.Actions
-//	if actions.EnableDownload() {
-//		enableDownload = true
-//	}
-//	if actions.EnableUpload() {
-//		enableUpload = true
-//	}
-//	zParser := zmodem.New()
-//	zParser.FileEventCallback = s.ZmodemFileTransferEvent
-//	protocol := s.connOpts.authInfo.Protocol
-//	filterRules := s.connOpts.authInfo.CommandFilterACLs
-//	platform := s.connOpts.authInfo.Platform
-//	// 过滤规则排序
-//	sort.Sort(model.CommandACLs(filterRules))
-//	parser := Parser{
-//		id:             s.ID,
-//		protocolType:   protocol,
-//		jmsService:     s.jmsService,
-//		cmdFilterACLs:  filterRules,
-//		enableDownload: enableDownload,
-//		enableUpload:   enableUpload,
-//		zmodemParser:   zParser,
-//		i18nLang:       s.connOpts.i18nLang,
-//		platform:       &platform,
-//	}
-//	parser.initial()
-//	return &parser
-//}
+func (s *ProxyServer) loadCommandFilters() error {
+	// 日志会话没有命令需要过滤
+	if s.connOpts.logs != nil {
+		return nil
+	}
+	acls, err := s.jmsService.ListSessionCommandFilters(context.Background(),
+		s.connOpts.authInfo.User, s.connOpts.authInfo.Asset)
+	if err != nil {
+		return err
+	}
+	s.cmdFilterACLs = acls
+	return nil
+}
+
+func (s *ProxyServer) GetFilterParser() *Parser {
+	// 日志会话没有命令可以记录, 也不允许传输文件
+	if s.connOpts.logs != nil {
+		return NewParser(s.ID, nil, nil, nil)
+	}
+	parser := NewParser(s.ID, s.cmdFilterACLs, s.recordCommand, s.reviewCommand)
+	// 只读会话丢弃用户输入, 无法完成传输握手, 直接拦截
+	parser.zmodemPolicy = ZmodemPolicy{
+		Upload:   s.connOpts.authInfo.Upload && !s.connOpts.authInfo.ReadOnly,
+		Download: s.connOpts.authInfo.Download && !s.connOpts.authInfo.ReadOnly,
+		MaxSize:  config.GetConf().ZipMaxSizeBytes(),
+	}
+	parser.zmodemRecordFunc = s.recordZmodemFile
+	return parser
+}
+
+// recordZmodemFile 将会话中 rz/sz 传输的文件记录到文件传输日志
+func (s *ProxyServer) recordZmodemFile(transferType string, file *zmodem.ZFileInfo, success bool, msg string) {
+	user := s.connOpts.authInfo.User
+	asset := s.connOpts.authInfo.Asset
+	log := &entity.FTPLog{
+		SessionID:   s.ID,
+		UserRef:     user.ID,
+		User:        user.String(),
+		RemoteAddr:  s.UserConn.RemoteAddr(),
+		ClusterName: asset.ClusterName,
+		Namespace:   asset.Namespace,
+		PodName:     asset.PodName,
+		Operate:     entity.FTPOperateDownload,
+		IsSuccess:   success,
+		Message:     msg,
+		Timestamp:   time.Now(),
+	}
+	if transferType == zmodem.TransferUpload {
+		log.Operate = entity.FTPOperateUpload
+	}
+	if info := s.connOpts.k8sContainer; info != nil {
+		log.Container = info.Container
+	}
+	if file != nil {
+		log.Path = file.Filename
+		log.Filesize = file.Size
+	}
+	klog.Infof("Session[%s] zmodem %s %s (%d bytes) success: %t", s.ID, log.Operate, log.Path, log.Filesize, success)
+	go s.jmsService.RecordFTPLog(context.Background(), log)
+}
+
+func (s *ProxyServer) recordCommand(cmd *entity.Command) {
+	user := s.connOpts.authInfo.User
+	asset := s.connOpts.authInfo.Asset
+	cmd.SessionID = s.ID
+	cmd.UserRef = user.ID
+	cmd.User = user.Username
+	cmd.ClusterName = asset.ClusterName
+	cmd.Namespace = asset.Namespace
+	cmd.PodName = asset.PodName
+	go s.jmsService.RecordCommand(context.Background(), cmd)
+}
+
+// reviewCommand 发起命令审批并等待审批结果
+func (s *ProxyServer) reviewCommand(ctx context.Context, command string, acl *entity.CommandFilterACL) (bool, string) {
+	user := s.connOpts.authInfo.User
+	asset := s.connOpts.authInfo.Asset
+	reason := fmt.Sprintf("matched command filter %s", acl.Name)
+	req, err := s.jmsService.CreateCommandReviewRequest(ctx, user, asset, s.ID, command, reason)
+	if err != nil {
+		klog.Errorf("Session[%s] create command review failed: %s", s.ID, err)
+		return false, fmt.Sprintf("Create command review failed: %s", err)
+	}
+	req, err = s.jmsService.WaitAccessRequest(ctx, req.ID)
+	if err != nil {
+		if errors.Is(err, context.Canceled) {
+			return false, "Command review canceled"
+		}
+		return false, fmt.Sprintf("Command `%s` was %s, canceled", command, err)
+	}
+	msg := fmt.Sprintf("Command review %d %s by %s", req.ID, req.Status, req.Reviewer)
+	if req.ReviewComment != "" {
+		msg = fmt.Sprintf("%s: %s", msg, req.ReviewComment)
+	}
+	return req.Status == entity.AccessRequestApproved, msg
+}
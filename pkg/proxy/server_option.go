@@ -3,6 +3,7 @@ package proxy
 import (
 	"fmt"
 	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/kubernetes/conn"
 )
 
 type ConnectionOption func(options *ConnectionOptions)
@@ -19,9 +20,24 @@ func ConnectTokenAuthInfo(authInfo *entity.ConnectInfo) ConnectionOption {
 	}
 }
 
+func ConnectShareMode(mode string) ConnectionOption {
+	return func(opts *ConnectionOptions) {
+		opts.shareMode = mode
+	}
+}
+
+// ConnectLogs 查看容器日志而不是登录容器
+func ConnectLogs(logOpts *conn.LogOptions) ConnectionOption {
+	return func(opts *ConnectionOptions) {
+		opts.logs = logOpts
+	}
+}
+
 type ConnectionOptions struct {
 	authInfo     *entity.ConnectInfo
 	k8sContainer *ContainerInfo
+	shareMode    string
+	logs         *conn.LogOptions
 }
 
 type ContainerInfo struct {
@@ -5,9 +5,11 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
 	"github.com/daicheng123/kubejump/pkg/exchange"
 	"github.com/daicheng123/kubejump/pkg/srvconn"
 	"github.com/daicheng123/kubejump/pkg/utils"
+	"github.com/daicheng123/kubejump/pkg/zmodem"
 	"k8s.io/klog/v2"
 	"sync/atomic"
 	"time"
@@ -29,6 +31,8 @@ type SwitchSession struct {
 
 	pausedStatus atomic.Bool // 暂停状态
 
+	exitReason string // 会话结束原因, Bridge 返回后有效
+
 	notifyMsgChan chan *exchange.RoomMessage
 
 	MaxSessionTime time.Time
@@ -45,6 +49,13 @@ func (s *SwitchSession) Terminate(username string) {
 	klog.Infof("Session[%s] receive terminate task from %s", s.ID, username)
 }
 
+func (s *SwitchSession) loadOperator() string {
+	if username, ok := s.currentOperator.Load().(string); ok {
+		return username
+	}
+	return ""
+}
+
 func (s *SwitchSession) setOperator(username string) {
 	s.currentOperator.Store(username)
 }
@@ -54,9 +65,10 @@ func (s *SwitchSession) PauseOperation(username string) {
 	s.setOperator(username)
 	klog.Infof("Session[%s] receive pause task from %s", s.ID, username)
 	p, _ := json.Marshal(map[string]string{"user": username})
-	s.notifyMsgChan <- &exchange.RoomMessage{
-		Event: exchange.PauseEvent,
-		Body:  p,
+	// 会话已结束时不再阻塞任务下发方
+	select {
+	case s.notifyMsgChan <- &exchange.RoomMessage{Event: exchange.PauseEvent, Body: p}:
+	case <-s.ctx.Done():
 	}
 }
 
@@ -65,24 +77,27 @@ func (s *SwitchSession) ResumeOperation(username string) {
 	s.setOperator(username)
 	klog.Infof("Session[%s] receive resume task from %s", s.ID, username)
 	p, _ := json.Marshal(map[string]string{"user": username})
-	s.notifyMsgChan <- &exchange.RoomMessage{
-		Event: exchange.ResumeEvent,
-		Body:  p,
+	// 会话已结束时不再阻塞任务下发方
+	select {
+	case s.notifyMsgChan <- &exchange.RoomMessage{Event: exchange.ResumeEvent, Body: p}:
+	case <-s.ctx.Done():
 	}
 }
 
 // Bridge 桥接两个链接
 func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerConnection) (err error) {
 
-	//parser := s.proxy.GetFilterParser()
-	//klog.Infof("Conn[%s] create ParseEngine success", userConn.ID())
-	//replayRecorder := s.proxy.GetReplayRecorder()
-	//klog.Infof("Conn[%s] create replay success", userConn.ID())
+	parser := s.proxy.GetFilterParser()
+	parser.paused = s.pausedStatus.Load
+	klog.Infof("Conn[%s] create ParseEngine success", userConn.ID())
+	replayRecorder := s.proxy.GetReplayRecorder()
+	klog.Infof("Conn[%s] create replay success", userConn.ID())
+	defer replayRecorder.End()
 	srvInChan := make(chan []byte, 1)
 	done := make(chan struct{})
 	userInputMessageChan := make(chan *exchange.RoomMessage, 1)
 	// 处理数据流
-	userOutChan, srvOutChan := ParseStream(userInputMessageChan, srvInChan, done)
+	userOutChan, srvOutChan := parser.ParseStream(userInputMessageChan, srvInChan, done)
 
 	defer func() {
 		//close(done)
@@ -119,25 +134,33 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 			validBytes := buf[:nr]
 			if nr > 0 {
 				bufferLen := buffer.Len()
-				if bufferLen > 0 || nr == maxLen {
-					buffer.Write(buf[:nr])
-					validBytes = validBytes[:0]
-				}
-				remainBytes := buffer.Bytes()
-				for len(remainBytes) > 0 {
-					r, size := utf8.DecodeRune(remainBytes)
-					if r == utf8.RuneError {
-						// utf8 max 4 bytes
-						if len(remainBytes) <= 3 {
-							break
+				if parser.InZmodemSession() {
+					// ZMODEM 传输的是二进制数据, 不按 utf8 分包
+					if bufferLen > 0 {
+						validBytes = append(append([]byte{}, buffer.Bytes()...), validBytes...)
+						buffer.Reset()
+					}
+				} else {
+					if bufferLen > 0 || nr == maxLen {
+						buffer.Write(buf[:nr])
+						validBytes = validBytes[:0]
+					}
+					remainBytes := buffer.Bytes()
+					for len(remainBytes) > 0 {
+						r, size := utf8.DecodeRune(remainBytes)
+						if r == utf8.RuneError {
+							// utf8 max 4 bytes
+							if len(remainBytes) <= 3 {
+								break
+							}
 						}
+						validBytes = append(validBytes, remainBytes[:size]...)
+						remainBytes = remainBytes[size:]
+					}
+					buffer.Reset()
+					if len(remainBytes) > 0 {
+						buffer.Write(remainBytes)
 					}
-					validBytes = append(validBytes, remainBytes[:size]...)
-					remainBytes = remainBytes[size:]
-				}
-				buffer.Reset()
-				if len(remainBytes) > 0 {
-					buffer.Write(remainBytes)
 				}
 				select {
 				case srvInChan <- validBytes:
@@ -159,6 +182,11 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 		close(srvInChan)
 	}()
 	user := s.proxy.connOpts.authInfo.User
+	readOnly := s.proxy.connOpts.authInfo.ReadOnly
+	if readOnly {
+		msg := "Read-only session, input is disabled. Press Ctrl-] to exit"
+		utils.IgnoreErrWriteString(userConn, utils.WrapperWarn(msg))
+	}
 	meta := exchange.MetaMessage{
 		UserId:     user.Name,
 		User:       user.String(),
@@ -166,47 +194,41 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 		RemoteAddr: userConn.RemoteAddr(),
 		TerminalId: userConn.ID(),
 		Primary:    true,
-		Writable:   true,
+		Writable:   !readOnly,
 	}
 	room.Broadcast(&exchange.RoomMessage{
 		Event: exchange.ShareJoin,
 		Meta:  meta,
 	})
-	//if parser.zmodemParser != nil {
-	//	parser.zmodemParser.FireStatusEvent = func(event zmodem.StatusEvent) {
-	//		msg := exchange.RoomMessage{Event: exchange.ActionEvent}
-	//		switch event {
-	//		case zmodem.StartEvent:
-	//			msg.Body = []byte(exchange.ZmodemStartEvent)
-	//		case zmodem.EndEvent:
-	//			msg.Body = []byte(exchange.ZmodemEndEvent)
-	//		default:
-	//			msg.Body = []byte(event)
-	//		}
-	//		room.Broadcast(&msg)
-	//	}
-	//}
+	parser.zmodemParser.FireStatusEvent = func(event zmodem.StatusEvent) {
+		msg := exchange.RoomMessage{Event: exchange.ActionEvent, Meta: meta}
+		switch event {
+		case zmodem.StartEvent:
+			msg.Body = []byte(exchange.ZmodemStartEvent)
+		case zmodem.EndEvent:
+			msg.Body = []byte(exchange.ZmodemEndEvent)
+		default:
+			msg.Body = []byte(event)
+		}
+		room.Broadcast(&msg)
+	}
 	go func() {
 		for {
 			buf := make([]byte, 1024)
 			nr, err := userConn.Read(buf)
+			// 只读会话仅响应退出快捷键
+			if nr > 0 && readOnly {
+				if bytes.IndexByte(buf[:nr], utils.CharDetach) >= 0 {
+					klog.Infof("Session[%s] read-only user detach", s.ID)
+					break
+				}
+				nr = 0
+			}
+			// 回车等待回显后再发送由 parser 处理
 			if nr > 0 {
-				index := bytes.IndexFunc(buf[:nr], func(r rune) bool {
-					return r == '\r'
-				})
-				//if index <= 0 || !parser.NeedRecord() {
-				//	room.Receive(&exchange.RoomMessage{
-				//		Event: exchange.DataEvent, Body: buf[:nr],
-				//		Meta: meta})
-				//} else {
 				room.Receive(&exchange.RoomMessage{
-					Event: exchange.DataEvent, Body: buf[:index],
+					Event: exchange.DataEvent, Body: buf[:nr],
 					Meta: meta})
-				time.Sleep(time.Millisecond * 100)
-				room.Receive(&exchange.RoomMessage{
-					Event: exchange.DataEvent, Body: buf[index:nr],
-					Meta: meta})
-				//}
 			}
 			if err != nil {
 				klog.Errorf("Session[%s] user read err: %s", s.ID, err)
@@ -226,9 +248,10 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 		case now := <-tick.C:
 			if s.MaxSessionTime.Before(now) {
 				msg := "Session max time reached, disconnect"
+				s.exitReason = entity.ExitReasonMaxTime
 				klog.Infof("Session[%s] max session time reached, disconnect", s.ID)
 				msg = utils.WrapperWarn(msg)
-				//replayRecorder.Record([]byte(msg))
+				replayRecorder.Record([]byte("\n\r" + msg))
 				room.Broadcast(&exchange.RoomMessage{Event: exchange.DataEvent, Body: []byte("\n\r" + msg)})
 				return
 			}
@@ -236,17 +259,19 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 			outTime := lastActiveTime.Add(maxIdleTime)
 			if now.After(outTime) {
 				msg := fmt.Sprintf("Connect idle more than %d minutes, disconnect", s.MaxIdleTime)
+				s.exitReason = entity.ExitReasonIdle
 				klog.Infof("Session[%s] idle more than %d minutes, disconnect", s.ID, s.MaxIdleTime)
 				msg = utils.WrapperWarn(msg)
-				//replayRecorder.Record([]byte(msg))
+				replayRecorder.Record([]byte("\n\r" + msg))
 				room.Broadcast(&exchange.RoomMessage{Event: exchange.DataEvent, Body: []byte("\n\r" + msg)})
 				return
 			}
 			if s.proxy.CheckPermissionExpired(now) {
 				msg := "Permission has expired, disconnect"
+				s.exitReason = entity.ExitReasonExpired
 				klog.Infof("Session[%s] permission has expired, disconnect", s.ID)
 				msg = utils.WrapperWarn(msg)
-				//replayRecorder.Record([]byte(msg))
+				replayRecorder.Record([]byte("\n\r" + msg))
 				room.Broadcast(&exchange.RoomMessage{Event: exchange.DataEvent, Body: []byte("\n\r" + msg)})
 				return
 			}
@@ -255,8 +280,9 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 		case <-s.ctx.Done():
 			//adminUser := s.loadOperator()
 			msg := "Terminated by admin"
+			s.exitReason = entity.ExitReasonAdminTerminate
 			msg = utils.WrapperWarn(msg)
-			//replayRecorder.Record([]byte(msg))
+			replayRecorder.Record([]byte("\n\r" + msg))
 			klog.Infof("Session[%s]: %s", s.ID, msg)
 			room.Broadcast(&exchange.RoomMessage{Event: exchange.DataEvent, Body: []byte("\n\r" + msg)})
 			return
@@ -266,6 +292,7 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 				return
 			}
 			_ = srvConn.SetWinSize(win.Width, win.Height)
+			replayRecorder.RecordResize(win.Width, win.Height)
 			klog.Infof("Session[%s] Window server change: %d*%d",
 				s.ID, win.Width, win.Height)
 			p, _ := json.Marshal(win)
@@ -279,9 +306,10 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 			if !ok {
 				return
 			}
-			//if parser.NeedRecord() {
-			//	replayRecorder.Record(p)
-			//}
+			// 传输的文件内容不写入录像
+			if !parser.InZmodemSession() {
+				replayRecorder.Record(p)
+			}
 			msg := exchange.RoomMessage{
 				Event: exchange.DataEvent,
 				Body:  p,
@@ -317,49 +345,3 @@ func (s *SwitchSession) Bridge(userConn UserConnection, srvConn srvconn.ServerCo
 		lastActiveTime = time.Now()
 	}
 }
-
-func ParseStream(userInChan chan *exchange.RoomMessage, srvInChan <-chan []byte, closed <-chan struct{}) (userOut, srvOut <-chan []byte) {
-	userOutputChan := make(chan []byte, 1)
-	srvOutputChan := make(chan []byte, 1)
-	//lastActiveTime := time.Now()
-	go func() {
-		defer func() {
-			close(userOutputChan)
-			close(srvOutputChan)
-		}()
-		for {
-			select {
-			case <-closed:
-				return
-			case msg, ok := <-userInChan:
-				if !ok {
-					return
-				}
-				var b []byte
-				switch msg.Event {
-				case exchange.DataEvent:
-					b = msg.Body
-				}
-				if len(b) == 0 {
-					continue
-				}
-				select {
-				case <-closed:
-					return
-				case userOutputChan <- b:
-				}
-
-			case b, ok := <-srvInChan:
-				if !ok {
-					return
-				}
-				select {
-				case <-closed:
-					return
-				case srvOutputChan <- b:
-				}
-			}
-		}
-	}()
-	return userOutputChan, srvOutputChan
-}
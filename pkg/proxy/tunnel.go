@@ -0,0 +1,79 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/kubernetes/conn"
+	"github.com/daicheng123/kubejump/pkg/session"
+	"k8s.io/klog/v2"
+	"time"
+)
+
+// Forward 将用户的端口转发通道连接到 pod 的 port 端口, 记录为 tunnel 类型的会话, 支持管理员终断.
+// 转发连接建立后才调用 accept 接受通道, 只有此前的失败会返回 error, 由调用方拒绝通道
+func (s *ProxyServer) Forward(port int, accept func() error) error {
+	s.sessionInfo.Type = entity.TUNNELType
+	s.sessionInfo.Asset = fmt.Sprintf("%s:%d", s.sessionInfo.Asset, port)
+	pfConn, err := conn.NewPortForwardConnection(port, s.containerOptions(s.connOpts.authInfo.Asset.Cluster)...)
+	if err != nil {
+		return err
+	}
+	defer pfConn.Close()
+	if err = accept(); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(s.UserConn.Context())
+	defer cancel()
+	terminated := make(chan string, 1)
+	traceSession := session.NewSession(s.sessionInfo, func(task *entity.TerminalTask) error {
+		if task.Name != entity.TaskKillSession {
+			return fmt.Errorf("tunnel session unsupported task %s", task.Name)
+		}
+		select {
+		case terminated <- task.Kwargs.TerminatedBy:
+		default:
+		}
+		return nil
+	})
+	session.AddSession(traceSession)
+	defer session.RemoveSession(traceSession)
+	s.jmsService.CreateSession(context.Background(), s.sessionInfo)
+	klog.Infof("Conn[%s] forward to %s", s.UserConn.ID(), s.sessionInfo.Asset)
+
+	done := make(chan error, 1)
+	go func() {
+		done <- pfConn.Forward(ctx, s.UserConn)
+	}()
+	tick := time.NewTicker(30 * time.Second)
+	defer tick.Stop()
+	exitReason := entity.ExitReasonNormal
+	var terminatedBy string
+	for done != nil {
+		select {
+		case err = <-done:
+			done = nil
+		case now := <-tick.C:
+			if s.CheckPermissionExpired(now) {
+				klog.Infof("Session[%s] permission has expired, disconnect", s.ID)
+				exitReason = entity.ExitReasonExpired
+				cancel()
+			}
+		case terminatedBy = <-terminated:
+			klog.Infof("Session[%s] receive terminate task from %s", s.ID, terminatedBy)
+			exitReason = entity.ExitReasonAdminTerminate
+			cancel()
+		}
+	}
+	if err != nil && ctx.Err() == nil {
+		klog.Errorf("Session[%s] forward to %s failed: %s", s.ID, s.sessionInfo.Asset, err)
+	}
+
+	dateEnd := time.Now()
+	s.sessionInfo.DateEnd = &dateEnd
+	s.sessionInfo.ExitReason = exitReason
+	s.sessionInfo.TerminatedBy = terminatedBy
+	s.jmsService.FinishSession(context.Background(), s.sessionInfo)
+	return nil
+}
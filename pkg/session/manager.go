@@ -1,6 +1,12 @@
 package session
 
-import "sync"
+import (
+	"encoding/json"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"github.com/daicheng123/kubejump/pkg/exchange"
+	"k8s.io/klog/v2"
+	"sync"
+)
 
 var (
 	sessManager = newSessionManager()
@@ -15,12 +21,57 @@ func GetAliveSessions() []string {
 	return sessManager.Range()
 }
 
+func GetSessions() []*Session {
+	return sessManager.List()
+}
+
+// AddSession 登记本实例的在线会话, 同时保存到 exchange 供所有实例查询
 func AddSession(s *Session) {
 	sessManager.Add(s.ID, s)
+	info, _ := json.Marshal(s.Session)
+	exchange.StoreSession(s.ID, info)
 }
 
 func RemoveSession(s *Session) {
 	sessManager.Delete(s.ID)
+	exchange.DeleteSession(s.ID)
+}
+
+// ListAllSessions 列出所有实例上的在线会话
+func ListAllSessions() []*entity.Session {
+	infos := exchange.ListSessions()
+	sessions := make([]*entity.Session, 0, len(infos))
+	for _, info := range infos {
+		var sess entity.Session
+		if err := json.Unmarshal(info, &sess); err != nil {
+			klog.Errorf("Unmarshal session info failed: %s", err)
+			continue
+		}
+		sessions = append(sessions, &sess)
+	}
+	return sessions
+}
+
+// SendTask 将任务下发给会话所在的实例, 会话不存在时返回 exchange.ErrSessionNotFound
+func SendTask(sid string, task *entity.TerminalTask) error {
+	body, err := json.Marshal(task)
+	if err != nil {
+		return err
+	}
+	return exchange.SendSessionTask(sid, body)
+}
+
+// HandleTask 在本实例执行会话任务, 注册为 exchange 的任务处理函数
+func HandleTask(sid string, body []byte) (bool, error) {
+	sess, ok := GetSessionById(sid)
+	if !ok {
+		return false, nil
+	}
+	var task entity.TerminalTask
+	if err := json.Unmarshal(body, &task); err != nil {
+		return true, err
+	}
+	return true, sess.HandleTask(&task)
 }
 
 func newSessionManager() *sessionManager {
@@ -62,3 +113,13 @@ func (s *sessionManager) Range() []string {
 
 	return sids
 }
+
+func (s *sessionManager) List() []*Session {
+	s.Lock()
+	defer s.Unlock()
+	sessions := make([]*Session, 0, len(s.data))
+	for _, sess := range s.data {
+		sessions = append(sessions, sess)
+	}
+	return sessions
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const uploadTimeout = 10 * time.Minute
+
+// HTTPReplayStorage 以 PUT <URL>/<target> 的方式上传录像
+type HTTPReplayStorage struct {
+	URL     string
+	Headers map[string]string
+}
+
+func (h *HTTPReplayStorage) Upload(gZipFile, target string) error {
+	fd, err := os.Open(gZipFile)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	info, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+
+	reqURL := strings.TrimSuffix(h.URL, "/") + "/" + target
+	req, err := http.NewRequest(http.MethodPut, reqURL, fd)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", "application/gzip")
+	for k, v := range h.Headers {
+		req.Header.Set(k, v)
+	}
+	return doUpload(req)
+}
+
+func (h *HTTPReplayStorage) TypeName() string {
+	return entity.ReplayStorageHTTP
+}
+
+// UploadStatusError 存储服务返回非 2xx 状态码
+type UploadStatusError struct {
+	URL        string
+	StatusCode int
+	Status     string
+	Body       string
+}
+
+func (e *UploadStatusError) Error() string {
+	return fmt.Sprintf("upload %s failed: %s %s", e.URL, e.Status, e.Body)
+}
+
+// Temporary 4xx 为请求本身的问题, 如签名错误、无权限, 重试也不会成功
+func (e *UploadStatusError) Temporary() bool {
+	return e.StatusCode < 400 || e.StatusCode >= 500
+}
+
+func doUpload(req *http.Request) error {
+	client := &http.Client{Timeout: uploadTimeout}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return &UploadStatusError{URL: req.URL.String(), StatusCode: resp.StatusCode, Status: resp.Status, Body: string(body)}
+	}
+	return nil
+}
@@ -0,0 +1,49 @@
+package storage
+
+import (
+	"github.com/daicheng123/kubejump/internal/entity"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// LocalReplayStorage 将录像归档到本地目录, RootPath 为空时保留在录像目录中
+type LocalReplayStorage struct {
+	RootPath string
+}
+
+func (l *LocalReplayStorage) Upload(gZipFile, target string) error {
+	if l.RootPath == "" {
+		return nil
+	}
+	dst := filepath.Join(l.RootPath, target)
+	if err := os.MkdirAll(filepath.Dir(dst), os.ModePerm); err != nil {
+		return err
+	}
+	// 跨文件系统时 rename 会失败, 此时退化为拷贝
+	if err := os.Rename(gZipFile, dst); err == nil {
+		return nil
+	}
+	return copyFile(gZipFile, dst)
+}
+
+func (l *LocalReplayStorage) TypeName() string {
+	return entity.ReplayStorageLocal
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		_ = out.Close()
+		return err
+	}
+	return out.Close()
+}
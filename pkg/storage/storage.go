@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"fmt"
+	"github.com/daicheng123/kubejump/internal/entity"
+	"strings"
+)
+
+// ReplayStorage 会话录像的持久化存储, target 为相对路径, 如 2006-01-02/<session id>.cast.gz
+type ReplayStorage interface {
+	Upload(gZipFile, target string) error
+	TypeName() string
+}
+
+func NewReplayStorage(conf entity.ReplayConfig) (ReplayStorage, error) {
+	switch strings.ToLower(conf.TypeName) {
+	case entity.ReplayStorageS3:
+		if conf.Bucket == "" || conf.Endpoint == "" {
+			return nil, fmt.Errorf("s3 replay storage requires endpoint and bucket")
+		}
+		return &S3ReplayStorage{
+			Endpoint:  conf.Endpoint,
+			Region:    conf.Region,
+			Bucket:    conf.Bucket,
+			AccessKey: conf.AccessKey,
+			SecretKey: conf.SecretKey,
+		}, nil
+	case entity.ReplayStorageHTTP:
+		if conf.URL == "" {
+			return nil, fmt.Errorf("http replay storage requires url")
+		}
+		return &HTTPReplayStorage{
+			URL:     conf.URL,
+			Headers: conf.Headers,
+		}, nil
+	case entity.ReplayStorageLocal, "":
+		return &LocalReplayStorage{RootPath: conf.RootPath}, nil
+	default:
+		return nil, fmt.Errorf("unsupported replay storage type %s", conf.TypeName)
+	}
+}
@@ -712,6 +712,22 @@ func (t *Terminal) ReadPassword(prompt string) (line string, err error) {
 	return
 }
 
+// ReadLineWithPrompt temporarily changes the prompt and reads a line of input
+// from the terminal.
+func (t *Terminal) ReadLineWithPrompt(prompt string) (line string, err error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	oldPrompt := t.prompt
+	t.prompt = []rune(prompt)
+
+	line, err = t.readLine()
+
+	t.prompt = oldPrompt
+
+	return
+}
+
 // ReadLine returns a line of input from the terminal.
 func (t *Terminal) ReadLine() (line string, err error) {
 	t.lock.Lock()
@@ -18,16 +18,36 @@ const (
 
 	ParamErrorMsg = "参数绑定失败, 请检查数据类型"
 
-	//LoginCheckErrorMsg     = "用户名或密码错误"
+	LoginCheckErrorMsg = "用户名或密码错误"
 	//UserRegisterFailMsg    = "用户注册失败"
 	//UserNameEmptyMsg       = "用户不能为空"
 	//UserPassEmptyMsg       = "密码不能为空"
 	//UserDisableMsg         = "用户已被禁用"
-	//ForbiddenMsg           = "无权访问该资源"
+	ForbiddenMsg           = "无权访问该资源"
 	InternalServerErrorMsg = "服务器内部错误"
 
 	CreateK8SClusterErrorMsg = "创建K8S集群失败"
 
+	CreateUserErrorMsg = "创建用户失败"
+	UpdateUserErrorMsg = "更新用户失败"
+	QueryUserErrorMsg  = "查询用户失败"
+
+	CreateUserGroupErrorMsg = "创建用户组失败"
+	UpdateUserGroupErrorMsg = "更新用户组失败"
+	QueryUserGroupErrorMsg  = "查询用户组失败"
+
+	CreatePermissionErrorMsg = "创建授权规则失败"
+	UpdatePermissionErrorMsg = "更新授权规则失败"
+	QueryPermissionErrorMsg  = "查询授权规则失败"
+
+	QueryAccessRequestErrorMsg  = "查询访问申请失败"
+	ReviewAccessRequestErrorMsg = "审批访问申请失败"
+
+	SessionTaskErrorMsg  = "会话任务执行失败"
+	QuerySessionErrorMsg = "查询会话历史失败"
+
+	QueryFTPLogErrorMsg = "查询文件传输日志失败"
+
 	//LDAPUserLoginFailedMsg = "登录失败，请检查您的用户名和密码!"
 	//LDAPUserNotFoundMsg    = "用户不存在"
 )
@@ -38,15 +58,35 @@ var CustomError = map[int]string{
 
 	ParamError: ParamErrorMsg,
 
-	//AuthError:           LoginCheckErrorMsg,
+	AuthError: LoginCheckErrorMsg,
 	//UserRegisterFail:    UserRegisterFailMsg,
 	//UserNameEmpty:       UserNameEmptyMsg,
 	//UserPassEmpty:       UserPassEmptyMsg,
 	//UserDisable:         UserDisableMsg,
-	//Forbidden:           ForbiddenMsg,
+	Forbidden:           ForbiddenMsg,
 	InternalServerError: InternalServerErrorMsg,
 
 	CreateK8SClusterError: CreateK8SClusterErrorMsg,
+
+	CreateUserError: CreateUserErrorMsg,
+	UpdateUserError: UpdateUserErrorMsg,
+	QueryUserError:  QueryUserErrorMsg,
+
+	CreateUserGroupError: CreateUserGroupErrorMsg,
+	UpdateUserGroupError: UpdateUserGroupErrorMsg,
+	QueryUserGroupError:  QueryUserGroupErrorMsg,
+
+	CreatePermissionError: CreatePermissionErrorMsg,
+	UpdatePermissionError: UpdatePermissionErrorMsg,
+	QueryPermissionError:  QueryPermissionErrorMsg,
+
+	QueryAccessRequestError:  QueryAccessRequestErrorMsg,
+	ReviewAccessRequestError: ReviewAccessRequestErrorMsg,
+
+	SessionTaskError:  SessionTaskErrorMsg,
+	QuerySessionError: QuerySessionErrorMsg,
+
+	QueryFTPLogError: QueryFTPLogErrorMsg,
 	//
 	//LDAPUserLoginFailed: LDAPUserLoginFailedMsg,
 	//LDAPUserNotFound:    LDAPUserNotFoundMsg,
@@ -84,15 +124,41 @@ const (
 
 	ParamError = 8000
 
-	//AuthError           = 1000
+	AuthError = 1000
 	//UserRegisterFail    = 1003
 	//UserNameEmpty       = 1004
 	//UserPassEmpty       = 1005
 	//UserDisable         = 1006
-	//Forbidden           = http.StatusForbidden
+	Forbidden           = http.StatusForbidden
 	InternalServerError = http.StatusInternalServerError
 
 	CreateK8SClusterError = 2000
+
+	CreateUserError = 2100
+	UpdateUserError = 2101
+	QueryUserError  = 2102
+
+	CreateUserGroupError = 2110
+	UpdateUserGroupError = 2111
+	QueryUserGroupError  = 2112
+
+	CreatePermissionError = 2200
+	UpdatePermissionError = 2201
+	QueryPermissionError  = 2202
+
+	QueryAccessRequestError  = 2300
+	ReviewAccessRequestError = 2301
+
+	QueryCommandError = 2400
+
+	CreateCommandFilterError = 2500
+	UpdateCommandFilterError = 2501
+	QueryCommandFilterError  = 2502
+
+	SessionTaskError  = 2600
+	QuerySessionError = 2601
+
+	QueryFTPLogError = 2700
 	//
 	//LDAPUserLoginFailed = 3000
 	//LDAPUserNotFound    = 3001
@@ -0,0 +1,18 @@
+package utils
+
+import "golang.org/x/crypto/bcrypt"
+
+// HashPassword 使用 bcrypt 生成密码哈希, 数据库中只保存哈希值
+func HashPassword(password string) (string, error) {
+	hashed, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hashed), nil
+}
+
+// IsHashedPassword 判断是否为 bcrypt 哈希, 否则视为历史遗留的明文密码
+func IsHashedPassword(secret string) bool {
+	_, err := bcrypt.Cost([]byte(secret))
+	return err == nil
+}
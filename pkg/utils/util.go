@@ -11,6 +11,7 @@ const (
 	CharTab       = "\t"
 	CharNewLine   = "\r\n"
 	CharCleanLine = '\x15'
+	CharDetach    = '\x1d' // Ctrl-], 只读或加入的会话中退出
 )
 
 const (
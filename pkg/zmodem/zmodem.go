@@ -0,0 +1,299 @@
+package zmodem
+
+import (
+	"bytes"
+	"path"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+type StatusEvent string
+
+const (
+	StartEvent StatusEvent = "ZMODEM_START"
+	EndEvent   StatusEvent = "ZMODEM_END"
+)
+
+const (
+	TransferUpload   = "upload"   // 服务端执行 rz, 用户上传文件
+	TransferDownload = "download" // 服务端执行 sz, 用户下载文件
+)
+
+const (
+	zdle  = 0x18
+	zrub0 = 'l'
+	zrub1 = 'm'
+
+	// 数据子包的结束标志, 前缀为 ZDLE
+	zcrce = 'h'
+	zcrcg = 'i'
+	zcrcq = 'j'
+	zcrcw = 'k'
+
+	// fileFrameMaxSize ZFILE 帧中文件信息的长度上限, 超过时放弃解析
+	fileFrameMaxSize = 4096
+	// tailSize 保留上一段数据的末尾, 用于识别跨段的帧标志
+	tailSize = 8
+)
+
+var (
+	// sz 发出的 ZRQINIT 帧与 rz 发出的 ZRINIT 帧
+	zrqinitHeader = []byte("**\x18B00")
+	zrinitHeader  = []byte("**\x18B01")
+	zfinHeader    = []byte("**\x18B08")
+	// 下载时接收方回复的 ZRPOS 帧, 上传时发送方以十六进制帧头发出的 ZFILE 帧
+	zrposHeader    = []byte("**\x18B09")
+	zfileHexHeader = []byte("**\x18B04")
+
+	// ZFILE 使用二进制帧头, 分别为 16 位与 32 位 CRC
+	zfileBin16Header = []byte("*\x18A\x04")
+	zfileBin32Header = []byte("*\x18C\x04")
+
+	cancelSequence = []byte{zdle, zdle, zdle, zdle, zdle}
+
+	// AbortSession 发送给双方以中止传输, 5 个 CAN 加 5 个退格
+	AbortSession = []byte("\x18\x18\x18\x18\x18\x08\x08\x08\x08\x08")
+)
+
+// DetectTransfer 在服务端输出中识别 rz/sz 发出的初始帧, 返回传输方向, 未识别到时为空
+func DetectTransfer(b []byte) string {
+	switch {
+	case bytes.Contains(b, zrqinitHeader):
+		return TransferDownload
+	case bytes.Contains(b, zrinitHeader):
+		return TransferUpload
+	}
+	return ""
+}
+
+// IsHandshake 用户终端是否回复了 transferType 方向的握手帧, 服务端输出中的初始帧可能只是普通数据,
+// 只有用户终端回复后才是真正的 ZMODEM 会话
+func IsHandshake(transferType string, b []byte) bool {
+	if transferType == TransferDownload {
+		return bytes.Contains(b, zrinitHeader) || bytes.Contains(b, zrposHeader)
+	}
+	return bytes.Contains(b, zrqinitHeader) || bytes.Contains(b, zfileHexHeader) ||
+		bytes.Contains(b, zfileBin16Header) || bytes.Contains(b, zfileBin32Header)
+}
+
+// ZFileInfo 发送方在 ZFILE 帧中声明的文件名及大小, 大小未知时为 -1
+type ZFileInfo struct {
+	Filename string
+	Size     int64
+}
+
+// ZmodemParser 跟踪会话中的 ZMODEM 传输, 只识别帧不修改数据
+type ZmodemParser struct {
+	FireStatusEvent func(event StatusEvent)
+
+	status       atomic.Bool
+	canceled     bool
+	transferType string
+
+	// 是否已开始传输文件, 之前的握手阶段允许用户 Ctrl-C 退出
+	fileStarted bool
+	overAndOut  bool
+
+	outputTail []byte
+	inputTail  []byte
+
+	// 正在收集的 ZFILE 帧
+	fileFrame []byte
+	framing   bool
+}
+
+func (z *ZmodemParser) IsStartSession() bool {
+	return z.status.Load()
+}
+
+func (z *ZmodemParser) TransferType() string {
+	return z.transferType
+}
+
+// FileStarted 发送方是否已声明文件, 此前用户的终端可能并不支持 ZMODEM
+func (z *ZmodemParser) FileStarted() bool {
+	return z.fileStarted
+}
+
+// TrimOverAndOut 去掉上传结束后发送方的 "OO", 仅对会话结束后的第一段输入生效
+func (z *ZmodemParser) TrimOverAndOut(b []byte) []byte {
+	if !z.overAndOut {
+		return b
+	}
+	z.overAndOut = false
+	return bytes.TrimPrefix(b, []byte("OO"))
+}
+
+// IsCanceled 最近一次会话是否被任一方取消
+func (z *ZmodemParser) IsCanceled() bool {
+	return z.canceled
+}
+
+func (z *ZmodemParser) Start(transferType string) {
+	z.transferType = transferType
+	z.canceled = false
+	z.fileStarted = false
+	z.overAndOut = false
+	z.outputTail, z.inputTail = nil, nil
+	z.fileFrame, z.framing = nil, false
+	z.status.Store(true)
+	z.fireEvent(StartEvent)
+}
+
+// Cancel 中止当前会话, 调用方负责向双方发送 AbortSession
+func (z *ZmodemParser) Cancel() {
+	z.canceled = true
+	z.end()
+}
+
+func (z *ZmodemParser) end() {
+	if z.status.CompareAndSwap(true, false) {
+		z.fireEvent(EndEvent)
+	}
+}
+
+func (z *ZmodemParser) fireEvent(event StatusEvent) {
+	if z.FireStatusEvent != nil {
+		z.FireStatusEvent(event)
+	}
+}
+
+// Parse 解析会话中一个方向的数据, 返回发送方新声明的文件. 接收方回复 ZFIN 或任一方发出取消序列时会话结束.
+// 只在 ParseStream 所在的 goroutine 中调用, 仅 status 需要并发读取
+func (z *ZmodemParser) Parse(b []byte, fromServer bool) []*ZFileInfo {
+	if !z.IsStartSession() {
+		return nil
+	}
+	tail := &z.inputTail
+	if fromServer {
+		tail = &z.outputTail
+	}
+	data := append(append([]byte{}, *tail...), b...)
+	if len(data) > tailSize {
+		*tail = data[len(data)-tailSize:]
+	} else {
+		*tail = data
+	}
+
+	if bytes.Contains(data, cancelSequence) {
+		z.Cancel()
+		return nil
+	}
+	var files []*ZFileInfo
+	// 下载时文件信息由服务端发出, 上传时由用户发出
+	if fromServer == (z.transferType == TransferDownload) {
+		files = z.parseFileFrames(data, len(data)-len(b))
+	}
+	// 接收方回复 ZFIN 表示会话结束, 上传时用户随后发送的 "OO" 不应作为 shell 的输入
+	if fromServer == (z.transferType == TransferUpload) && bytes.Contains(data, zfinHeader) {
+		z.overAndOut = z.transferType == TransferUpload
+		z.end()
+	}
+	return files
+}
+
+// parseFileFrames 收集 ZFILE 帧并解析文件信息, start 为新数据在 data 中的起始位置
+func (z *ZmodemParser) parseFileFrames(data []byte, start int) []*ZFileInfo {
+	var files []*ZFileInfo
+	chunk := data[start:]
+	for {
+		if !z.framing {
+			index, headerLen := indexFileHeader(data)
+			// 帧头已在上一段数据中处理过
+			if index < 0 || index+headerLen <= start {
+				return files
+			}
+			z.framing = true
+			z.fileStarted = true
+			z.fileFrame = z.fileFrame[:0]
+			chunk = data[index:]
+			data, start = chunk, 0
+		}
+		z.fileFrame = append(z.fileFrame, chunk...)
+		info, consumed, ok := decodeFileFrame(z.fileFrame)
+		if !ok {
+			if len(z.fileFrame) > fileFrameMaxSize {
+				z.framing = false
+				z.fileFrame = nil
+			}
+			return files
+		}
+		z.framing = false
+		files = append(files, info)
+		remain := z.fileFrame[consumed:]
+		z.fileFrame = nil
+		data, chunk, start = remain, remain, 0
+	}
+}
+
+func indexFileHeader(data []byte) (int, int) {
+	if index := bytes.Index(data, zfileBin16Header); index >= 0 {
+		return index, len(zfileBin16Header)
+	}
+	if index := bytes.Index(data, zfileBin32Header); index >= 0 {
+		return index, len(zfileBin32Header)
+	}
+	return -1, 0
+}
+
+// decodeFileFrame 解析以 ZFILE 二进制帧头开始的数据, 返回文件信息及已消耗的字节数, 数据不完整时 ok 为 false
+func decodeFileFrame(frame []byte) (info *ZFileInfo, consumed int, ok bool) {
+	// 帧头后依次为 4 字节标志与 2 或 4 字节 CRC
+	skip := 4 + 2
+	if bytes.HasPrefix(frame, zfileBin32Header) {
+		skip = 4 + 4
+	}
+	var (
+		decoded []byte
+		escaped bool
+	)
+	for i := len(zfileBin16Header); i < len(frame); i++ {
+		c := frame[i]
+		if escaped {
+			escaped = false
+			switch c {
+			case zcrce, zcrcg, zcrcq, zcrcw:
+				if skip > 0 {
+					return nil, 0, false
+				}
+				return parseFileInfo(decoded), i + 1, true
+			case zrub0:
+				c = 0x7f
+			case zrub1:
+				c = 0xff
+			default:
+				c ^= 0x40
+			}
+		} else {
+			switch c {
+			case zdle:
+				escaped = true
+				continue
+			case 0x11, 0x13, 0x91, 0x93:
+				// 流控字符不属于数据
+				continue
+			}
+		}
+		if skip > 0 {
+			skip--
+			continue
+		}
+		decoded = append(decoded, c)
+	}
+	return nil, 0, false
+}
+
+// parseFileInfo 数据子包格式为 "文件名\0大小 修改时间 权限 ...\0"
+func parseFileInfo(data []byte) *ZFileInfo {
+	info := &ZFileInfo{Size: -1}
+	name, rest, _ := bytes.Cut(data, []byte{0})
+	info.Filename = path.Base(string(name))
+	rest, _, _ = bytes.Cut(rest, []byte{0})
+	if fields := strings.Fields(string(rest)); len(fields) > 0 {
+		if size, err := strconv.ParseInt(fields[0], 10, 64); err == nil {
+			info.Size = size
+		}
+	}
+	return info
+}